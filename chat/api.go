@@ -0,0 +1,287 @@
+package chat
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/HanmaDevin/schlama/config"
+	"github.com/HanmaDevin/schlama/ollama"
+	"github.com/HanmaDevin/schlama/server"
+	"github.com/HanmaDevin/schlama/session"
+)
+
+// The JSON API under /api/v1 uses the same conversations as the browser, so
+// scripts and editor plugins can take part in them.
+
+// apiConversation is a conversation in the responses of the API. Messages is
+// the active branch and left out of lists.
+type apiConversation struct {
+	ID           string            `json:"id"`
+	Title        string            `json:"title,omitempty"`
+	Source       string            `json:"source"`
+	Model        string            `json:"model"`
+	Created      time.Time         `json:"created"`
+	Updated      time.Time         `json:"updated"`
+	MessageCount int               `json:"message_count"`
+	Messages     []session.Message `json:"messages,omitempty"`
+}
+
+// apiChunk is a line of a streamed answer. The last line is done and holds
+// the stored answer, or it holds the error.
+type apiChunk struct {
+	Content string           `json:"content,omitempty"`
+	Done    bool             `json:"done,omitempty"`
+	Message *session.Message `json:"message,omitempty"`
+	Error   string           `json:"error,omitempty"`
+}
+
+func toAPI(s *session.Session, withMessages bool) apiConversation {
+	branch := s.Branch()
+	c := apiConversation{
+		ID:           s.ID,
+		Title:        s.Title,
+		Source:       s.Source,
+		Model:        s.Model,
+		Created:      s.Created,
+		Updated:      s.Updated,
+		MessageCount: len(branch),
+	}
+	if withMessages {
+		c.Messages = branch
+	}
+	return c
+}
+
+func apiConversationsHandler(w http.ResponseWriter, r *http.Request) {
+	sessions, err := session.List()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to list conversations: "+err.Error())
+		return
+	}
+	list := []apiConversation{}
+	for _, s := range sessions {
+		list = append(list, toAPI(s, false))
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"conversations": list})
+}
+
+// maxRequestSize limits the bodies of API requests without images.
+const maxRequestSize = 1 << 20
+
+// readBody decodes the JSON body of r into v. The body may be at most limit
+// bytes large. It writes an error and returns false if that fails.
+func readBody(w http.ResponseWriter, r *http.Request, v any, limit int64) bool {
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, limit)).Decode(v)
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		writeError(w, http.StatusRequestEntityTooLarge, "The request body is larger than "+formatSize(limit))
+		return false
+	case err != nil:
+		writeError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// apiCreateHandler starts a conversation with the model of the body or the
+// default model.
+func apiCreateHandler(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Model string `json:"model"`
+		Title string `json:"title"`
+	}
+	if r.ContentLength != 0 && !readBody(w, r, &body, maxRequestSize) {
+		return
+	}
+
+	cfg, err := config.ReadConfig()
+	if body.Model != "" && err == nil {
+		if !apiModelExists(w, body.Model) {
+			return
+		}
+		cfg, err = config.ReadConfigFor(body.Model)
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to read config: "+err.Error())
+		return
+	}
+
+	s := session.New(cfg, "api")
+	s.Title = body.Title
+	if err := session.Save(s); err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to store conversation: "+err.Error())
+		return
+	}
+	track(s)
+	w.Header().Set("Location", "/api/v1/conversations/"+s.ID)
+	writeJSON(w, http.StatusCreated, toAPI(s, true))
+}
+
+func apiConversationHandler(w http.ResponseWriter, r *http.Request) {
+	c, ok := apiOpen(w, r)
+	if !ok {
+		return
+	}
+	c.Lock()
+	defer c.Unlock()
+	writeJSON(w, http.StatusOK, toAPI(c.Session, true))
+}
+
+func apiMessagesHandler(w http.ResponseWriter, r *http.Request) {
+	c, ok := apiOpen(w, r)
+	if !ok {
+		return
+	}
+	c.Lock()
+	defer c.Unlock()
+	writeJSON(w, http.StatusOK, map[string]any{"messages": c.Branch()})
+}
+
+// apiSendHandler adds a prompt to a conversation and answers it. With
+// "stream": true the answer is sent as JSON lines while it is generated.
+func apiSendHandler(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Content string   `json:"content"`
+		Images  []string `json:"images"`
+		Stream  bool     `json:"stream"`
+	}
+	// images are sent inline
+	if !readBody(w, r, &body, maxUploadSize) {
+		return
+	}
+	if body.Content == "" {
+		writeError(w, http.StatusBadRequest, "content cannot be empty")
+		return
+	}
+	settings, err := config.Current()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to read config: "+err.Error())
+		return
+	}
+	images, err := downscaleImages(body.Images, settings.Web.MaxImageSize)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	c, ok := apiOpen(w, r)
+	if !ok {
+		return
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	head := c.Head
+	c.Add(ollama.Message{Role: "user", Content: body.Content, Images: images})
+
+	if !body.Stream {
+		if err := answer(r.Context(), c, head, func(string) { server.Extend(w) }); err != nil {
+			if r.Context().Err() == nil {
+				writeError(w, http.StatusBadGateway, "Failed to get response from Ollama: "+err.Error())
+			}
+			return
+		}
+		m, _ := c.Get(c.Head)
+		writeJSON(w, http.StatusOK, map[string]any{"message": m})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	rc := http.NewResponseController(w)
+	enc := json.NewEncoder(w)
+	send := func(chunk apiChunk) {
+		if err := enc.Encode(chunk); err != nil {
+			log.Warn("Failed to stream answer: " + err.Error())
+		}
+		rc.Flush()
+	}
+	err = answer(r.Context(), c, head, func(chunk string) {
+		server.Extend(w)
+		send(apiChunk{Content: chunk})
+	})
+	if err != nil {
+		if r.Context().Err() == nil {
+			send(apiChunk{Error: "Failed to get response from Ollama: " + err.Error()})
+		}
+		return
+	}
+	m, _ := c.Get(c.Head)
+	send(apiChunk{Done: true, Message: &m})
+}
+
+func apiModelsHandler(w http.ResponseWriter, r *http.Request) {
+	models, err := ollama.LocalModels()
+	if err != nil {
+		writeError(w, http.StatusBadGateway, "Failed to get local models: "+err.Error())
+		return
+	}
+	settings, err := config.Current()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to read config: "+err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"models": models, "default": settings.Model})
+}
+
+// apiConfigHandler shows the effective settings and where they come from.
+// The password of the chat is not shown.
+func apiConfigHandler(w http.ResponseWriter, r *http.Request) {
+	settings, err := config.Explain()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to read config: "+err.Error())
+		return
+	}
+	settings = slices.DeleteFunc(settings, func(s config.Setting) bool { return s.Key == "web.password" })
+	type setting struct {
+		Key    string `json:"key"`
+		Value  string `json:"value"`
+		Source string `json:"source"`
+	}
+	list := []setting{}
+	for _, s := range settings {
+		list = append(list, setting{Key: s.Key, Value: s.Value, Source: s.Source})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"profile": config.ActiveProfile(), "settings": list})
+}
+
+// apiOpen returns the conversation of the id in the path and writes an error
+// if there is none.
+func apiOpen(w http.ResponseWriter, r *http.Request) (*conversation, bool) {
+	c, err := open(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return nil, false
+	}
+	return c, true
+}
+
+func apiModelExists(w http.ResponseWriter, model string) bool {
+	models, err := ollama.LocalModels()
+	if err != nil {
+		writeError(w, http.StatusBadGateway, "Failed to get local models: "+err.Error())
+		return false
+	}
+	if !slices.Contains(models, model) {
+		writeError(w, http.StatusBadRequest, "Unknown model "+model)
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Error("Failed to write response: " + err.Error())
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	log.Warn(msg)
+	writeJSON(w, status, map[string]string{"error": msg})
+}
@@ -0,0 +1,264 @@
+package chat
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/HanmaDevin/schlama/config"
+)
+
+// Ways to log in to the web chat.
+const (
+	AuthNone     = "none"
+	AuthToken    = "token"
+	AuthPassword = "password"
+)
+
+// AuthModes are the valid values of Options.Auth.
+var AuthModes = []string{AuthNone, AuthToken, AuthPassword}
+
+const sessionCookie = "schlama_session"
+
+type csrfKey struct{}
+
+// guard lets only logged in browsers use the chat. With a token the printed
+// URL logs in once, with a password the login form has to be filled in.
+// Every login gets a session cookie and a CSRF token that POST requests
+// have to send along. API clients send the API key as bearer token instead.
+type guard struct {
+	mode     string
+	password string
+	base     string
+	// remote is set if the chat listens beyond localhost.
+	remote bool
+	// apiKey is the password, or a random key with token logins.
+	apiKey string
+
+	mu sync.Mutex
+	// token is the one-time token of the login URL.
+	token string
+	// logins maps session cookies to their CSRF tokens.
+	logins map[string]string
+}
+
+func newGuard(mode, password, base string, remote bool) *guard {
+	g := &guard{mode: mode, password: password, base: base, remote: remote, logins: map[string]string{}}
+	switch mode {
+	case AuthToken:
+		g.token = randomToken()
+		g.apiKey = randomToken()
+	case AuthPassword:
+		g.apiKey = password
+	}
+	return g
+}
+
+func randomToken() string {
+	b := make([]byte, 32)
+	// rand.Read never fails, it crashes the program instead
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// loginURL is the URL to open the chat with.
+func (g *guard) loginURL() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	switch g.mode {
+	case AuthToken:
+		return g.base + "/login?token=" + url.QueryEscape(g.token)
+	case AuthPassword:
+		return g.base + "/login"
+	default:
+		return g.base
+	}
+}
+
+// wrap rejects cross-site POST requests and, unless auth is off, requests of
+// browsers that are not logged in and of API clients without the API key.
+func (g *guard) wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		unsafe := r.Method != http.MethodGet && r.Method != http.MethodHead
+		if unsafe && !sameOrigin(r) {
+			http.Error(w, "Cross-site requests are not allowed", http.StatusForbidden)
+			return
+		}
+		if g.mode == AuthNone || r.URL.Path == "/login" || strings.HasPrefix(r.URL.Path, "/static/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if strings.HasPrefix(r.URL.Path, "/api/") && r.Header.Get("Authorization") != "" {
+			key, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if subtle.ConstantTimeCompare([]byte(key), []byte(g.apiKey)) != 1 {
+				http.Error(w, "Invalid API key", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		csrf, ok := g.login(r)
+		if !ok {
+			if r.Method == http.MethodGet && r.URL.Path == "/" {
+				http.Redirect(w, r, "/login", http.StatusSeeOther)
+				return
+			}
+			http.Error(w, "Not logged in", http.StatusUnauthorized)
+			return
+		}
+		if unsafe {
+			sent := r.Header.Get("X-CSRF-Token")
+			if sent == "" {
+				sent = r.PostFormValue("csrf")
+			}
+			if subtle.ConstantTimeCompare([]byte(sent), []byte(csrf)) != 1 {
+				http.Error(w, "Invalid CSRF token", http.StatusForbidden)
+				return
+			}
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), csrfKey{}, csrf)))
+	})
+}
+
+// sameOrigin reports whether the request was sent by a page of the chat.
+// Browsers always send Origin with POST requests, other clients may leave
+// it out.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && u.Host == r.Host
+}
+
+// login returns the CSRF token of the session cookie of r.
+func (g *guard) login(r *http.Request) (string, bool) {
+	cookie, err := r.Cookie(sessionCookie)
+	if err != nil {
+		return "", false
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	csrf, ok := g.logins[cookie.Value]
+	return csrf, ok
+}
+
+// csrfToken returns the CSRF token the page of r has to send, empty if auth
+// is off.
+func csrfToken(r *http.Request) string {
+	csrf, _ := r.Context().Value(csrfKey{}).(string)
+	return csrf
+}
+
+// start logs the browser in with a new session cookie.
+func (g *guard) start(w http.ResponseWriter, r *http.Request) {
+	id := randomToken()
+	g.mu.Lock()
+	g.logins[id] = randomToken()
+	g.mu.Unlock()
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    id,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   g.secureCookie(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// secureCookie reports whether the session cookie of r is only sent over
+// HTTPS. That is the case beyond localhost, as long as r came in over HTTPS
+// itself, directly or through a proxy. Browsers drop secure cookies of plain
+// HTTP pages, the login would not work at all.
+func (g *guard) secureCookie(r *http.Request) bool {
+	return g.remote && (r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https")
+}
+
+// loginHandler logs in with the token of the URL or shows the password form.
+func (g *guard) loginHandler(w http.ResponseWriter, r *http.Request) {
+	if _, ok := g.login(r); ok || g.mode == AuthNone {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+	if g.mode == AuthPassword {
+		g.renderLogin(w, http.StatusOK, "")
+		return
+	}
+
+	token := r.URL.Query().Get("token")
+	g.mu.Lock()
+	valid := token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(g.token)) == 1
+	if valid {
+		g.token = randomToken()
+	}
+	g.mu.Unlock()
+	if !valid {
+		g.renderLogin(w, http.StatusUnauthorized, "This link is invalid or was already used. Open the login URL printed by schlama chat.")
+		return
+	}
+	g.start(w, r)
+	log.Info("Logged in with the login URL, the next login needs " + g.loginURL())
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// passwordHandler checks the password of the login form.
+func (g *guard) passwordHandler(w http.ResponseWriter, r *http.Request) {
+	if g.mode != AuthPassword {
+		http.Error(w, "Password login is not enabled", http.StatusNotFound)
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(r.PostFormValue("password")), []byte(g.password)) != 1 {
+		// slow down guessing
+		time.Sleep(time.Second)
+		log.Warn("Failed login from " + r.RemoteAddr)
+		g.renderLogin(w, http.StatusUnauthorized, "Wrong password.")
+		return
+	}
+	g.start(w, r)
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// logoutHandler ends the session of the browser.
+func (g *guard) logoutHandler(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(sessionCookie); err == nil {
+		g.mu.Lock()
+		delete(g.logins, cookie.Value)
+		g.mu.Unlock()
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   g.secureCookie(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+	if g.mode == AuthToken {
+		g.renderLogin(w, http.StatusOK, "Logged out. Open the login URL printed by schlama chat to log in again.")
+		return
+	}
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
+
+func (g *guard) renderLogin(w http.ResponseWriter, status int, msg string) {
+	settings, err := config.Current()
+	if err != nil {
+		log.Error("Failed to read config: " + err.Error())
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := t.ExecuteTemplate(w, "login.html", data{Theme: settings.Web.Theme, Auth: g.mode, Error: msg}); err != nil {
+		log.Error("Failed to render login template: " + err.Error())
+	}
+}
@@ -1,44 +1,82 @@
 package chat
 
 import (
+	"cmp"
 	"embed"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"html/template"
 	"io"
+	"io/fs"
+	"net"
 	"net/http"
 	"os"
 	"os/exec"
 	"runtime"
+	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/charmbracelet/log"
 
 	"github.com/HanmaDevin/schlama/config"
+	"github.com/HanmaDevin/schlama/markdown"
 	"github.com/HanmaDevin/schlama/ollama"
+	"github.com/HanmaDevin/schlama/server"
+	"github.com/HanmaDevin/schlama/session"
 )
 
-//go:embed views/*.html
+//go:embed views/*.html static
 var views embed.FS
-var t, _ = template.New("").ParseFS(views, "views/*.html")
-
-var history = []ollama.Message{}
+var t, _ = template.New("").Funcs(template.FuncMap{
+	"markdown": markdown.HTML,
+	"size":     formatSize,
+	"imageURL": session.ImageURL,
+	"maxFileSize": func() int64 {
+		return maxFileSize
+	},
+}).ParseFS(views, "views/*.html")
 
 type data struct {
+	Theme        string
+	SessionID    string
 	CurrentModel string
-	Prompt       string
-	Resp         string
-	Models       []string
-	Error        string
+	Messages     []session.Message
+	// Message is the prompt that is edited.
+	Message session.Message
+	Models  []string
+	// Prompt and Results are the prompt and answers of a comparison.
+	Prompt  string
+	Results []ollama.Result
+	Error   string
+	// Auth is the login mode, CSRF the token POST requests have to send.
+	Auth string
+	CSRF string
+	// Conversations are listed in the sidebar, Query is their search.
+	Conversations []*session.Session
+	Query         string
 }
 
 func rootHandler(w http.ResponseWriter, r *http.Request) {
-	cfg := config.ReadConfig()
+	settings, err := config.Current()
+	if err != nil {
+		log.Error("Failed to read config: " + err.Error())
+		http.Error(w, "Failed to read config: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	c := current()
+	c.Lock()
 	data := data{
-		CurrentModel: cfg.Model,
+		Theme:        settings.Web.Theme,
+		SessionID:    c.ID,
+		CurrentModel: c.Model,
+		Messages:     c.Branch(),
+		CSRF:         csrfToken(r),
 	}
+	c.Unlock()
 
-	models, err := getLocalModels()
+	models, err := ollama.LocalModels()
 	if err != nil {
 		log.Error("Failed to get local models: " + err.Error())
 		http.Error(w, "Failed to get local models: "+err.Error(), http.StatusInternalServerError)
@@ -46,6 +84,10 @@ func rootHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	data.Models = models
+	data.Conversations, err = listConversations("")
+	if err != nil {
+		log.Error("Failed to list conversations: " + err.Error())
+	}
 
 	if err := t.ExecuteTemplate(w, "index.html", data); err != nil {
 		log.Error("Failed to render index template: " + err.Error())
@@ -60,129 +102,352 @@ func setModelHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	cfg := config.Config{
-		Model: r.FormValue("model"),
+	model := r.FormValue("model")
+	models, err := ollama.LocalModels()
+	if err != nil {
+		log.Error("Failed to get local models: " + err.Error())
+		http.Error(w, "Failed to get local models: "+err.Error(), http.StatusInternalServerError)
+		return
 	}
-	log.Infof("Setting model to %s...", cfg.Model)
-	if err := config.WriteConfig(cfg); err != nil {
-		log.Error("Failed to write config: " + err.Error())
-		http.Error(w, "Failed to write config: "+err.Error(), http.StatusInternalServerError)
+	if !slices.Contains(models, model) {
+		log.Warn("Unknown model " + model)
+		http.Error(w, "Unknown model "+model, http.StatusBadRequest)
+		return
+	}
+
+	// the model only changes for this conversation, the config file keeps
+	// the default for new ones
+	cfg, err := config.ReadConfigFor(model)
+	if err != nil {
+		log.Error("Failed to read config: " + err.Error())
+		http.Error(w, "Failed to read config: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
+	log.Infof("Switching conversation to %s...", model)
+	c := current()
+	c.Lock()
+	defer c.Unlock()
+	c.Configure(cfg)
+	if len(c.Messages) > 0 {
+		if err := session.Save(c.Session); err != nil {
+			log.Error("Failed to store conversation: " + err.Error())
+		}
+	}
 }
 
 func chatHandler(w http.ResponseWriter, r *http.Request) {
 	log.Info("Handling chat request...")
-	if err := r.ParseMultipartForm(200 << 20); err != nil {
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
 		log.Error("Failed to parse form: " + err.Error())
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "The files are larger than "+formatSize(maxUploadSize)+" together", http.StatusRequestEntityTooLarge)
+			return
+		}
 		http.Error(w, "Failed to parse form", http.StatusBadRequest)
 		return
 	}
-	cfg := config.ReadConfig()
-
-	data := data{
-		CurrentModel: cfg.Model,
-	}
-
 	prompt := r.FormValue("prompt")
 	if prompt == "" {
 		log.Warn("Prompt cannot be empty")
 		http.Error(w, "", http.StatusBadRequest)
-		data.Error = "Prompt cannot be empty"
 		return
 	}
-	for _, msg := range history {
-		cfg.Messages = append(cfg.Messages, msg)
+	settings, err := config.Current()
+	if err != nil {
+		log.Error("Failed to read config: " + err.Error())
+		http.Error(w, "Failed to read config: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	images, attachments, err := readUploads(r.MultipartForm.File["files"], settings.Web.MaxImageSize)
+	if err != nil {
+		uploadError(w, err)
+		return
 	}
 
-	msg := ollama.Message{}
-	msg.Role = "user"
-	msg.Content = prompt
+	c := current()
+	c.Lock()
+	defer c.Unlock()
+	head := c.Head
+	c.AddPrompt(prompt, images, attachments)
+	respond(w, r, c, head)
+}
 
-	form := r.MultipartForm
-	for _, fileHeader := range form.File["files"] {
-		file, err := fileHeader.Open()
-		if err != nil {
-			log.Error("Failed to open file: " + err.Error())
-			http.Error(w, "Failed to open file: "+err.Error(), http.StatusInternalServerError)
+// maxCompare is the number of models a comparison may ask.
+const maxCompare = 8
+
+// compareHandler asks several models the prompt at the same time. The
+// conversation so far is sent along, but the answers are not added to it.
+func compareHandler(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		log.Error("Failed to parse form: " + err.Error())
+		http.Error(w, "Failed to parse form", http.StatusBadRequest)
+		return
+	}
+	prompt := r.FormValue("prompt")
+	models := r.Form["models"]
+	if prompt == "" || len(models) < 2 {
+		log.Warn("Compare needs a prompt and at least two models")
+		http.Error(w, "Pick at least two models and enter a prompt", http.StatusBadRequest)
+		return
+	}
+	if len(models) > maxCompare {
+		http.Error(w, fmt.Sprintf("Pick at most %d models", maxCompare), http.StatusBadRequest)
+		return
+	}
+	local, err := ollama.LocalModels()
+	if err != nil {
+		log.Error("Failed to get local models: " + err.Error())
+		http.Error(w, "Failed to get local models: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	c := current()
+	c.Lock()
+	history := c.History()
+	c.Unlock()
+
+	var reqs []*ollama.Ollama
+	for _, model := range models {
+		if !slices.Contains(local, model) {
+			http.Error(w, "Unknown model "+model, http.StatusBadRequest)
 			return
 		}
-		defer file.Close()
-		content, err := io.ReadAll(file)
+		cfg, err := config.ReadConfigFor(model)
 		if err != nil {
-			log.Error("Failed to read file: " + err.Error())
-			http.Error(w, "Failed to read file: "+err.Error(), http.StatusInternalServerError)
+			log.Error("Failed to read config: " + err.Error())
+			http.Error(w, "Failed to read config: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
-		switch fileHeader.Header.Get("Content-Type") {
-		case "text/plain":
-			log.Info("Received text file: " + fileHeader.Filename)
-			msg.Content += "\n" + string(content)
-		case "image/png", "image/jpeg", "image/gif":
-			log.Info("Received image file: " + fileHeader.Filename)
-			encoded := encodeImageToBase64(content)
-			msg.Images = append(msg.Images, encoded)
-		case "text/html":
-			log.Info("Received HTML file: " + fileHeader.Filename)
-			msg.Content += "\n" + string(content)
-		case "application/json":
-			log.Info("Received JSON file: " + fileHeader.Filename)
-			msg.Content += "\n" + string(content)
-		case "text/xml", "application/xml":
-			log.Info("Received XML file: " + fileHeader.Filename)
-			msg.Content += "\n" + string(content)
-		case "application/x-shellscript":
-			log.Info("Received shell script file: " + fileHeader.Filename)
-			msg.Content += "\n" + string(content)
-		default:
-			log.Info("Received file with unknown content type: " + fileHeader.Header.Get("Content-Type"))
-			msg.Content += "\n" + string(content)
-		}
+		cfg.Messages = append(cfg.Messages, history...)
+		cfg.Messages = append(cfg.Messages, ollama.Message{Role: "user", Content: prompt})
+		reqs = append(reqs, cfg)
 	}
 
-	cfg.Messages = append(cfg.Messages, msg)
-	resp, err := ollama.GetResponse(cfg)
-	if err != nil {
+	log.Infof("Comparing %s...", strings.Join(models, ", "))
+	results := ollama.Compare(r.Context(), reqs, func() { server.Extend(w) })
+	render(w, "compare.html", data{Prompt: prompt, Results: results})
+}
+
+// markdownCSSHandler serves the stylesheet of rendered answers.
+func markdownCSSHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/css; charset=utf-8")
+	io.WriteString(w, markdown.CSS())
+}
+
+// messagesHandler renders the active branch of the conversation.
+func messagesHandler(w http.ResponseWriter, r *http.Request) {
+	c := current()
+	c.Lock()
+	defer c.Unlock()
+	render(w, "response.html", data{Messages: c.Branch()})
+}
+
+// editFormHandler renders the form that rewrites a prompt.
+func editFormHandler(w http.ResponseWriter, r *http.Request) {
+	c := current()
+	c.Lock()
+	defer c.Unlock()
+	m, ok := c.Get(messageID(r))
+	if !ok || m.Role != "user" {
+		http.Error(w, "Prompt not found", http.StatusNotFound)
+		return
+	}
+	render(w, "edit.html", data{Message: m})
+}
+
+// editHandler asks again with a rewritten prompt. The answer starts a new
+// branch, the old one is kept in the session.
+func editHandler(w http.ResponseWriter, r *http.Request) {
+	prompt := r.FormValue("prompt")
+	if prompt == "" {
+		log.Warn("Prompt cannot be empty")
+		http.Error(w, "", http.StatusBadRequest)
+		return
+	}
+
+	c := current()
+	c.Lock()
+	defer c.Unlock()
+	head := c.Head
+	if err := c.Edit(messageID(r), prompt); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	respond(w, r, c, head)
+}
+
+// regenerateHandler asks for a new answer to the prompt of an answer.
+func regenerateHandler(w http.ResponseWriter, r *http.Request) {
+	c := current()
+	c.Lock()
+	defer c.Unlock()
+	head := c.Head
+	if err := c.Regenerate(messageID(r)); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	respond(w, r, c, head)
+}
+
+// respond answers the active branch of c and renders it. If the model fails
+// or the browser cancels the request, head becomes the active message again.
+// c must be locked.
+func respond(w http.ResponseWriter, r *http.Request, c *conversation, head int) {
+	if err := answer(r.Context(), c, head, func(string) { server.Extend(w) }); err != nil {
+		if r.Context().Err() != nil {
+			// the tab was closed or the server shuts down, nobody reads the answer
+			log.Info("Request canceled, stopped the answer")
+			return
+		}
 		log.Error("Failed to get response from Ollama: " + err.Error())
 		http.Error(w, "Failed to get response from Ollama: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
+	// the sidebar shows the new or changed conversation
+	w.Header().Set("HX-Trigger", "conversations-changed")
+	render(w, "response.html", data{Messages: c.Branch()})
+}
+
+func render(w http.ResponseWriter, name string, data data) {
+	if err := t.ExecuteTemplate(w, name, data); err != nil {
+		log.Error("Failed to render " + name + ": " + err.Error())
+		http.Error(w, "Something went wrong :(", http.StatusInternalServerError)
+	}
+}
+
+func messageID(r *http.Request) int {
+	id, _ := strconv.Atoi(r.PathValue("id"))
+	return id
+}
 
-	data.Resp = resp
-	data.Prompt = prompt
+// exportHandler sends a conversation as a Markdown, JSON or HTML download.
+func exportHandler(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "md"
+	}
 
-	history = append(history, msg)
-	history = append(history, ollama.Message{
-		Role:    "assistant",
-		Content: resp,
-	})
+	c, err := open(r.PathValue("id"))
+	if err != nil {
+		log.Warn("Failed to load conversation: " + err.Error())
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
 
-	if err := t.ExecuteTemplate(w, "response.html", data); err != nil {
-		log.Error("Failed to render response template: " + err.Error())
-		http.Error(w, "Failed to render response: "+err.Error(), http.StatusInternalServerError)
+	var buf strings.Builder
+	c.Lock()
+	err = session.Render(&buf, c.Session, format)
+	c.Unlock()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	w.Header().Set("Content-Type", session.ContentType(format))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "schlama-"+c.ID+"."+format))
+	io.WriteString(w, buf.String())
+}
+
+// static holds the stylesheets and scripts of the chat, the page works
+// without a connection to a CDN.
+var static, _ = fs.Sub(views, "static")
+
+// Options control where the chat listens and whether a browser is opened.
+type Options struct {
+	// Addr is the address to bind, localhost if empty.
+	Addr string
+	// Port is the port to listen on, web.port of the config if 0.
+	Port int
+	// Open opens the chat in the browser once the server listens.
+	Open bool
+	// Auth is one of AuthModes. If empty, the chat asks for web.password
+	// when it is set and else for a token when it listens beyond localhost.
+	Auth string
 }
 
-func Start() {
+func Start(opts Options) error {
+	settings, err := config.Current()
+	if err != nil {
+		return err
+	}
+	cfg, err := config.ReadConfig()
+	if err != nil {
+		return err
+	}
+	active = track(session.New(cfg, "web"))
+
 	router := http.NewServeMux()
 	router.HandleFunc("GET /", rootHandler)
 	router.HandleFunc("POST /set-model", setModelHandler)
 	router.HandleFunc("POST /chat", chatHandler)
+	router.HandleFunc("GET /static/markdown.css", markdownCSSHandler)
+	router.Handle("GET /static/", http.StripPrefix("/static/", http.FileServerFS(static)))
+	router.HandleFunc("POST /compare", compareHandler)
+	router.HandleFunc("GET /messages", messagesHandler)
+	router.HandleFunc("GET /messages/{id}/edit", editFormHandler)
+	router.HandleFunc("POST /messages/{id}/edit", editHandler)
+	router.HandleFunc("POST /messages/{id}/regenerate", regenerateHandler)
+	router.HandleFunc("GET /sessions/{id}/export", exportHandler)
+	router.HandleFunc("GET /conversations", conversationsHandler)
+	router.HandleFunc("POST /conversations", newConversationHandler)
+	router.HandleFunc("POST /conversations/{id}/open", openConversationHandler)
+	router.HandleFunc("POST /conversations/{id}/pin", pinHandler)
+	router.HandleFunc("POST /conversations/{id}/rename", renameHandler)
+	router.HandleFunc("DELETE /conversations/{id}", deleteHandler)
+	router.HandleFunc("GET /api/v1/conversations", apiConversationsHandler)
+	router.HandleFunc("POST /api/v1/conversations", apiCreateHandler)
+	router.HandleFunc("GET /api/v1/conversations/{id}", apiConversationHandler)
+	router.HandleFunc("GET /api/v1/conversations/{id}/messages", apiMessagesHandler)
+	router.HandleFunc("POST /api/v1/conversations/{id}/messages", apiSendHandler)
+	router.HandleFunc("GET /api/v1/models", apiModelsHandler)
+	router.HandleFunc("GET /api/v1/config", apiConfigHandler)
 
-	server := &http.Server{
-		Addr:    ":8080",
-		Handler: router,
-	}
-
-	err := openURL("http://localhost:8080")
+	ln, err := server.Listen(opts.Addr, cmp.Or(opts.Port, settings.Web.Port))
 	if err != nil {
-		fmt.Println("Failed to open browser: " + err.Error())
-		os.Exit(1)
+		return err
+	}
+	addr := ln.Addr().(*net.TCPAddr)
+	mode := opts.Auth
+	switch {
+	case mode != "":
+	case settings.Web.Password != "":
+		mode = AuthPassword
+	case !addr.IP.IsLoopback():
+		mode = AuthToken
+	default:
+		mode = AuthNone
+	}
+	if mode == AuthPassword && settings.Web.Password == "" {
+		ln.Close()
+		return fmt.Errorf("password auth needs a password, set web.password or %s", config.EnvName("web.password"))
 	}
+	g := newGuard(mode, settings.Web.Password, server.URL(addr), !addr.IP.IsLoopback())
+	router.HandleFunc("GET /login", g.loginHandler)
+	router.HandleFunc("POST /login", g.passwordHandler)
+	router.HandleFunc("POST /logout", g.logoutHandler)
 
-	log.Info("Chat started in your browser at http://localhost:8080")
-	server.ListenAndServe()
+	srv := server.New(secure(g.wrap(router)))
+	url := g.loginURL()
+	log.Info("Chat started at " + url)
+	switch mode {
+	case AuthToken:
+		log.Info("API clients send the header 'Authorization: Bearer " + g.apiKey + "'")
+	case AuthPassword:
+		log.Info("API clients send the password in the header 'Authorization: Bearer <password>'")
+	}
+	switch {
+	case mode == AuthNone && !addr.IP.IsLoopback():
+		log.Warn("Auth is off, everyone who can reach " + addr.String() + " can use the chat")
+	case !addr.IP.IsLoopback():
+		log.Warn("Logins are sent unencrypted over plain HTTP, serve the chat behind an HTTPS proxy on untrusted networks")
+	}
+	if opts.Open {
+		if err := openURL(url); err != nil {
+			log.Warn("Failed to open browser, open the URL yourself: " + err.Error())
+		}
+	}
+	return server.Run(srv, ln)
 }
 
 func openURL(url string) error {
@@ -213,23 +478,6 @@ func openURL(url string) error {
 	return nil
 }
 
-func getLocalModels() ([]string, error) {
-	cmd := exec.Command("ollama", "list")
-	output, err := cmd.Output()
-	if err != nil {
-		return nil, err
-	}
-	lines := strings.Split(string(output), "\n")
-	var models []string
-	for _, line := range lines[1:] { // skip header
-		fields := strings.Fields(line)
-		if len(fields) > 0 {
-			models = append(models, fields[0])
-		}
-	}
-	return models, nil
-}
-
 func encodeImageToBase64(content []byte) string {
 	encoded := base64.StdEncoding.EncodeToString(content)
 	return encoded
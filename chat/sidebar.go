@@ -0,0 +1,161 @@
+package chat
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/HanmaDevin/schlama/config"
+	"github.com/HanmaDevin/schlama/session"
+)
+
+// listConversations returns the stored conversations that match query,
+// pinned ones first and then the most recent.
+func listConversations(query string) ([]*session.Session, error) {
+	sessions, err := session.List()
+	if err != nil {
+		return nil, err
+	}
+	if query != "" {
+		sessions = slices.DeleteFunc(sessions, func(s *session.Session) bool { return !s.Matches(query) })
+	}
+	// List sorts by time already, a stable sort keeps that order
+	slices.SortStableFunc(sessions, func(a, b *session.Session) int {
+		switch {
+		case a.Pinned == b.Pinned:
+			return 0
+		case a.Pinned:
+			return -1
+		default:
+			return 1
+		}
+	})
+	return sessions, nil
+}
+
+// conversationsHandler renders the list of the sidebar, filtered by the
+// search query q.
+func conversationsHandler(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.FormValue("q"))
+	sessions, err := listConversations(query)
+	if err != nil {
+		log.Error("Failed to list conversations: " + err.Error())
+		http.Error(w, "Failed to list conversations: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	c := current()
+	render(w, "conversations.html", data{SessionID: c.ID, Conversations: sessions, Query: query})
+}
+
+// newConversationHandler starts an empty conversation with the default
+// model and shows it.
+func newConversationHandler(w http.ResponseWriter, r *http.Request) {
+	cfg, err := config.ReadConfig()
+	if err != nil {
+		log.Error("Failed to read config: " + err.Error())
+		http.Error(w, "Failed to read config: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	show(w, track(session.New(cfg, "web")))
+}
+
+// openConversationHandler shows a stored conversation in the browser.
+func openConversationHandler(w http.ResponseWriter, r *http.Request) {
+	c, err := open(r.PathValue("id"))
+	if err != nil {
+		log.Warn("Failed to load conversation: " + err.Error())
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	show(w, c)
+}
+
+// show makes c the conversation of the browser and reloads the page. The
+// conversation shown before is dropped from the store if it is still empty,
+// it was never stored.
+func show(w http.ResponseWriter, c *conversation) {
+	storeMu.Lock()
+	prev := active
+	active = c
+	storeMu.Unlock()
+	w.Header().Set("HX-Refresh", "true")
+
+	if prev == nil || prev == c {
+		return
+	}
+	prev.Lock()
+	empty := len(prev.Messages) == 0
+	prev.Unlock()
+	if empty {
+		forget(prev)
+	}
+}
+
+// pinHandler pins or unpins a conversation.
+func pinHandler(w http.ResponseWriter, r *http.Request) {
+	change(w, r, func(c *conversation) {
+		c.Pinned = !c.Pinned
+	})
+}
+
+// renameHandler sets the title the user entered in the prompt of htmx. An
+// empty title falls back to the first prompt.
+func renameHandler(w http.ResponseWriter, r *http.Request) {
+	change(w, r, func(c *conversation) {
+		c.Title = strings.TrimSpace(r.Header.Get("HX-Prompt"))
+	})
+}
+
+// change applies fn to the conversation of the path, stores it and renders
+// the list again.
+func change(w http.ResponseWriter, r *http.Request, fn func(c *conversation)) {
+	c, err := open(r.PathValue("id"))
+	if err != nil {
+		log.Warn("Failed to load conversation: " + err.Error())
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	c.Lock()
+	fn(c)
+	err = session.Save(c.Session)
+	c.Unlock()
+	if err != nil {
+		log.Error("Failed to store conversation: " + err.Error())
+		http.Error(w, "Failed to store conversation: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	conversationsHandler(w, r)
+}
+
+// deleteHandler removes a conversation. If the browser shows it, a new one
+// takes its place. A new conversation may not be stored yet, it is only
+// dropped from the store then.
+func deleteHandler(w http.ResponseWriter, r *http.Request) {
+	c, err := open(r.PathValue("id"))
+	if err != nil {
+		log.Warn("Failed to load conversation: " + err.Error())
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	// wait for an answer that is still generated
+	c.Lock()
+	err = session.Delete(c.ID)
+	c.Unlock()
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		log.Error(err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	forget(c)
+	log.Info("Deleted conversation " + c.ID)
+
+	if current() == c {
+		newConversationHandler(w, r)
+		return
+	}
+	conversationsHandler(w, r)
+}
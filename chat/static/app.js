@@ -0,0 +1,173 @@
+// Show spinner while the model answers
+document.body.addEventListener('htmx:beforeRequest', function (evt) {
+  if (evt.detail.elt.closest('[data-spinner]')) {
+    document.getElementById('loading-spinner').classList.remove('hidden');
+  }
+});
+
+// Hide spinner after the request, also when it failed
+document.body.addEventListener('htmx:afterRequest', function (evt) {
+  document.getElementById('loading-spinner').classList.add('hidden');
+  if (evt.detail.elt.id === 'form-prompt' && evt.detail.successful) {
+    evt.detail.elt.reset();
+  }
+});
+
+// Add a copy button to every code block of the answers
+function addCopyButtons(root) {
+  root.querySelectorAll('.markdown pre').forEach(function (pre) {
+    if (pre.parentElement.classList.contains('code-block')) {
+      return;
+    }
+    const wrapper = document.createElement('div');
+    wrapper.className = 'code-block';
+    pre.parentNode.insertBefore(wrapper, pre);
+    wrapper.appendChild(pre);
+
+    const button = document.createElement('button');
+    button.type = 'button';
+    button.className = 'copy-code btn btn-xs';
+    button.textContent = 'Copy';
+    button.addEventListener('click', function () {
+      navigator.clipboard.writeText(pre.innerText).then(function () {
+        button.textContent = 'Copied';
+        setTimeout(function () { button.textContent = 'Copy'; }, 1500);
+      });
+    });
+    wrapper.appendChild(button);
+  });
+}
+addCopyButtons(document);
+document.body.addEventListener('htmx:afterSwap', function (evt) {
+  addCopyButtons(evt.detail.target);
+});
+
+// Scroll to the newest message after the conversation is swapped in
+document.body.addEventListener('htmx:afterSwap', function (evt) {
+  if (evt.detail.target.id === "messages" || evt.detail.target.id === "comparisons") {
+    const chatWindow = document.getElementById("chat-window");
+    chatWindow.scrollTop = chatWindow.scrollHeight;
+  }
+});
+
+// Show the chosen files as chips, files over the size limit are marked and
+// block sending
+const fileInput = document.getElementById('files');
+const formError = document.getElementById('form-error');
+
+function formatSize(n) {
+  if (n >= 1 << 20) return (n / (1 << 20)).toFixed(1) + ' MB';
+  if (n >= 1 << 10) return (n / (1 << 10)).toFixed(1) + ' KB';
+  return n + ' B';
+}
+
+function showError(msg) {
+  formError.textContent = msg;
+  formError.classList.toggle('hidden', !msg);
+}
+
+function tooLarge() {
+  const max = Number(fileInput.dataset.maxSize);
+  return Array.from(fileInput.files).filter(function (f) { return f.size > max; });
+}
+
+// previews are the object URLs of the image chips, freed on every render
+let previews = [];
+
+function setFiles(files) {
+  const list = new DataTransfer();
+  files.forEach(function (f) { list.items.add(f); });
+  fileInput.files = list.files;
+  renderChips();
+}
+
+function renderChips() {
+  const chips = document.getElementById('attachment-chips');
+  chips.replaceChildren();
+  previews.forEach(URL.revokeObjectURL);
+  previews = [];
+  const max = Number(fileInput.dataset.maxSize);
+  const files = Array.from(fileInput.files);
+  files.forEach(function (file, i) {
+    const chip = document.createElement('span');
+    chip.className = file.size > max ? 'chip chip-error' : 'chip';
+    if (file.type.startsWith('image/')) {
+      const preview = document.createElement('img');
+      preview.src = URL.createObjectURL(file);
+      preview.alt = '';
+      previews.push(preview.src);
+      chip.appendChild(preview);
+    }
+    chip.appendChild(document.createTextNode(file.name + ' · ' + formatSize(file.size)));
+    const remove = document.createElement('button');
+    remove.type = 'button';
+    remove.textContent = '×';
+    remove.title = 'Remove ' + file.name;
+    remove.addEventListener('click', function () {
+      setFiles(files.filter(function (f, j) { return j !== i; }));
+    });
+    chip.appendChild(remove);
+    chips.appendChild(chip);
+  });
+  const large = tooLarge();
+  showError(large.length ? large.map(function (f) { return f.name; }).join(', ') + ' is larger than ' + formatSize(max) : '');
+}
+
+fileInput.addEventListener('change', renderChips);
+
+// Images pasted into the prompt and files dropped on the form are attached
+// like chosen files
+function addFiles(files) {
+  setFiles(Array.from(fileInput.files).concat(Array.from(files)));
+}
+
+document.getElementById('prompt').addEventListener('paste', function (evt) {
+  const images = Array.from(evt.clipboardData.files).filter(function (f) { return f.type.startsWith('image/'); });
+  if (images.length) {
+    evt.preventDefault();
+    addFiles(images);
+  }
+});
+
+const promptForm = document.getElementById('form-prompt');
+promptForm.addEventListener('dragover', function (evt) {
+  if (evt.dataTransfer.types.includes('Files')) {
+    evt.preventDefault();
+    promptForm.classList.add('drop-target');
+  }
+});
+promptForm.addEventListener('dragleave', function (evt) {
+  if (!promptForm.contains(evt.relatedTarget)) {
+    promptForm.classList.remove('drop-target');
+  }
+});
+promptForm.addEventListener('drop', function (evt) {
+  promptForm.classList.remove('drop-target');
+  if (evt.dataTransfer.files.length) {
+    evt.preventDefault();
+    addFiles(evt.dataTransfer.files);
+  }
+});
+promptForm.addEventListener('reset', function () {
+  setTimeout(renderChips);
+});
+
+// Don't send files that the server rejects anyway
+document.body.addEventListener('htmx:beforeRequest', function (evt) {
+  if (evt.detail.elt.id === 'form-prompt' && tooLarge().length) {
+    evt.preventDefault();
+    document.getElementById('loading-spinner').classList.add('hidden');
+  }
+});
+
+// Show why a prompt was rejected, e.g. an unsupported file
+document.body.addEventListener('htmx:responseError', function (evt) {
+  if (evt.detail.elt.id === 'form-prompt') {
+    showError(evt.detail.xhr.responseText.trim() || 'Failed to send the prompt');
+  }
+});
+document.body.addEventListener('htmx:afterRequest', function (evt) {
+  if (evt.detail.elt.id === 'form-prompt' && evt.detail.successful) {
+    showError('');
+  }
+});
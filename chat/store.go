@@ -0,0 +1,180 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/HanmaDevin/schlama/config"
+	"github.com/HanmaDevin/schlama/ollama"
+	"github.com/HanmaDevin/schlama/session"
+)
+
+// conversation is a conversation of the chat. It is locked while it changes
+// or is answered, so the browser and API clients don't get in each other's
+// way.
+type conversation struct {
+	sync.Mutex
+	*session.Session
+}
+
+var (
+	storeMu sync.Mutex
+	// conversations are the conversations used since the chat started, by id.
+	conversations = map[string]*conversation{}
+	// active is the conversation the browser shows.
+	active *conversation
+)
+
+// current returns the conversation the browser shows.
+func current() *conversation {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+	return active
+}
+
+// track keeps a new conversation in the store.
+func track(s *session.Session) *conversation {
+	c := &conversation{Session: s}
+	storeMu.Lock()
+	conversations[s.ID] = c
+	storeMu.Unlock()
+	return c
+}
+
+// tracked reports whether c is still in the store.
+func tracked(c *conversation) bool {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+	return conversations[c.ID] == c
+}
+
+// forget drops c from the store.
+func forget(c *conversation) {
+	storeMu.Lock()
+	if conversations[c.ID] == c {
+		delete(conversations, c.ID)
+	}
+	storeMu.Unlock()
+}
+
+// open returns the conversation with the given id, it is loaded from disk
+// the first time.
+func open(id string) (*conversation, error) {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+	if c, ok := conversations[id]; ok {
+		return c, nil
+	}
+	s, err := session.Load(id)
+	if err != nil {
+		return nil, err
+	}
+	// id may have been a prefix
+	if c, ok := conversations[s.ID]; ok {
+		return c, nil
+	}
+	c := &conversation{Session: s}
+	conversations[s.ID] = c
+	return c, nil
+}
+
+// answer asks the model of c to answer the active branch and adds the
+// answer. onChunk gets the answer while it is generated and may be nil. If
+// the model fails or ctx is canceled, head becomes the active message again.
+// c must be locked.
+func answer(ctx context.Context, c *conversation, head int, onChunk func(chunk string)) error {
+	rollback := func() {
+		if c.Head != head {
+			c.Drop(c.Head)
+		}
+		c.Checkout(head)
+	}
+
+	settings, err := config.Current()
+	if err != nil {
+		rollback()
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+	cfg, err := config.ReadConfigFor(c.Model)
+	if err != nil {
+		rollback()
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+	cfg.Messages = append(cfg.Messages, c.History()...)
+	cfg.Messages = ollama.TrimHistory(cfg.Messages, settings.History.MaxMessages)
+	resp, _, err := ollama.Chat(ctx, cfg, onChunk)
+	if err != nil {
+		rollback()
+		return err
+	}
+
+	c.Configure(cfg)
+	c.Add(ollama.Message{
+		Role:    "assistant",
+		Content: resp,
+	})
+	if branch := c.Branch(); c.Title == "" && len(branch) == 2 {
+		go autoTitle(c, c.Model, branch[0].Content, branch[1].Content)
+	}
+	if err := session.Save(c.Session); err != nil {
+		log.Error("Failed to store conversation: " + err.Error())
+	}
+	return nil
+}
+
+// titlePrompt asks a model to name a conversation after its first exchange.
+const titlePrompt = `Write a title of at most six words for the following conversation.
+Answer with the title only, without quotes.
+
+User: %s
+
+Assistant: %s`
+
+// titleTimeout limits the time a model gets to name a conversation.
+const titleTimeout = 2 * time.Minute
+
+// autoTitle asks model to name c after its first exchange of prompt and
+// reply. It runs in the background, so the answer is not held up, and the
+// sidebar shows the title on its next refresh. c must not be locked.
+func autoTitle(c *conversation, model, prompt, reply string) {
+	ctx, cancel := context.WithTimeout(context.Background(), titleTimeout)
+	defer cancel()
+	req := ollama.NewOllama()
+	req.Model = model
+	req.Options = map[string]any{"num_predict": 32, "temperature": 0.2}
+	req.Messages = []ollama.Message{{
+		Role:    "user",
+		Content: fmt.Sprintf(titlePrompt, truncate(prompt, 2000), truncate(reply, 2000)),
+	}}
+	title, _, err := ollama.Chat(ctx, req, nil)
+	if err != nil {
+		log.Warn("Failed to generate a title: " + err.Error())
+		return
+	}
+	title, _, _ = strings.Cut(strings.TrimSpace(title), "\n")
+	title = strings.TrimRight(strings.Trim(title, "\"'*# "), ".")
+
+	c.Lock()
+	defer c.Unlock()
+	// the user may have named or deleted the conversation in the meantime
+	if c.Title != "" || !tracked(c) {
+		return
+	}
+	c.Title = truncate(title, 80)
+	if err := session.Save(c.Session); err != nil {
+		log.Error("Failed to store conversation: " + err.Error())
+	}
+}
+
+// truncate shortens s to at most n runes.
+func truncate(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n])
+	}
+	return s
+}
@@ -0,0 +1,117 @@
+package chat
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/HanmaDevin/schlama/extract"
+	"github.com/HanmaDevin/schlama/imaging"
+	"github.com/HanmaDevin/schlama/session"
+)
+
+const (
+	// maxFileSize is the size limit of a single attached file.
+	maxFileSize = 20 << 20
+	// maxUploadSize is the size limit of a whole prompt with its files.
+	maxUploadSize = 100 << 20
+)
+
+// errUpload is an attachment the user has to fix, as opposed to a failure
+// of the server.
+type errUpload struct{ msg string }
+
+func (e errUpload) Error() string { return e.msg }
+
+// readUploads turns the attached files of a prompt into images and
+// attachments. The type of a file is sniffed from its content, the type the
+// browser sent is not trusted. Images are downscaled to maxImageSize.
+func readUploads(files []*multipart.FileHeader, maxImageSize int) ([]string, []session.Attachment, error) {
+	var images []string
+	var attachments []session.Attachment
+	for _, fh := range files {
+		if fh.Size > maxFileSize {
+			return nil, nil, errUpload{fmt.Sprintf("%s is larger than %s", fh.Filename, formatSize(maxFileSize))}
+		}
+		content, err := readUpload(fh)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read %s: %w", fh.Filename, err)
+		}
+
+		typ := extract.Type(fh.Filename, content)
+		a := session.Attachment{Name: fh.Filename, Type: typ, Size: fh.Size}
+		if extract.IsImage(typ) {
+			log.Info("Received image " + fh.Filename + " (" + typ + ")")
+			if content, err = imaging.Downscale(content, maxImageSize); err != nil {
+				return nil, nil, errUpload{fmt.Sprintf("%s: %s", fh.Filename, err)}
+			}
+			images = append(images, encodeImageToBase64(content))
+			attachments = append(attachments, a)
+			continue
+		}
+		a.Text, err = extract.Text(typ, content)
+		if errors.Is(err, extract.ErrUnsupported) {
+			return nil, nil, errUpload{fmt.Sprintf("%s is a binary file (%s), only text, PDFs, office documents and images can be attached", fh.Filename, typ)}
+		}
+		if err != nil {
+			return nil, nil, errUpload{fmt.Sprintf("%s: %s", fh.Filename, err)}
+		}
+		log.Info("Received file " + fh.Filename + " (" + typ + ")")
+		attachments = append(attachments, a)
+	}
+	return images, attachments, nil
+}
+
+// downscaleImages downscales base64 encoded images to maxImageSize.
+func downscaleImages(images []string, maxImageSize int) ([]string, error) {
+	scaled := make([]string, 0, len(images))
+	for i, encoded := range images {
+		content, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("image %d is not base64 encoded", i+1)
+		}
+		if content, err = imaging.Downscale(content, maxImageSize); err != nil {
+			return nil, fmt.Errorf("image %d: %w", i+1, err)
+		}
+		scaled = append(scaled, encodeImageToBase64(content))
+	}
+	return scaled, nil
+}
+
+func readUpload(fh *multipart.FileHeader) ([]byte, error) {
+	f, err := fh.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// uploadError writes err as the response of a failed upload.
+func uploadError(w http.ResponseWriter, err error) {
+	var e errUpload
+	if errors.As(err, &e) {
+		log.Warn("Rejected upload: " + e.msg)
+		http.Error(w, e.msg, http.StatusBadRequest)
+		return
+	}
+	log.Error(err.Error())
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
+
+// formatSize formats a size in bytes for people.
+func formatSize(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%d B", n)
+	}
+}
@@ -1,20 +1,55 @@
 package cmd
 
 import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
 	"github.com/HanmaDevin/schlama/chat"
 	"github.com/spf13/cobra"
 )
 
+var (
+	chatAddr   string
+	chatPort   int
+	chatOpen   bool
+	chatNoOpen bool
+	chatAuth   string
+)
+
 // chatCmd represents the chat command
 var chatCmd = &cobra.Command{
 	Use:   "chat",
 	Short: "Chat with local LLMs",
-	Long:  `Opens your browser with a chat interface for local LLMs.`,
+	Long: `Opens your browser with a chat interface for local LLMs.
+The chat only listens on localhost unless --addr is given. If the port is taken, a free port is used.
+When web.password is set, the chat asks for it. Otherwise a chat that listens beyond localhost prints a login URL
+with a one-time token.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		chat.Start()
+		if chatAuth != "" && !slices.Contains(chat.AuthModes, chatAuth) {
+			fmt.Println(Red("[Error]") + " --auth must be one of " + strings.Join(chat.AuthModes, ", "))
+			return
+		}
+		opts := chat.Options{
+			Addr: chatAddr,
+			Port: chatPort,
+			Open: chatOpen && !chatNoOpen,
+			Auth: chatAuth,
+		}
+		if err := chat.Start(opts); err != nil {
+			fmt.Println(Red("[Error] ") + err.Error())
+			os.Exit(1)
+		}
 	},
 }
 
 func init() {
+	chatCmd.Flags().StringVar(&chatAddr, "addr", "localhost", "Address to listen on, use 0.0.0.0 to allow other machines")
+	chatCmd.Flags().IntVarP(&chatPort, "port", "p", 0, "Port to listen on (default web.port of the config)")
+	chatCmd.Flags().BoolVar(&chatOpen, "open", true, "Open the chat in the browser")
+	chatCmd.Flags().BoolVar(&chatNoOpen, "no-open", false, "Only print the URL of the chat")
+	chatCmd.Flags().StringVar(&chatAuth, "auth", "", "Login with none, token or password (default password if web.password is set, token beyond localhost, else none)")
+	chatCmd.MarkFlagsMutuallyExclusive("open", "no-open")
 	rootCmd.AddCommand(chatCmd)
 }
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/HanmaDevin/schlama/config"
+	"github.com/HanmaDevin/schlama/ollama"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var (
+	compareModels []string
+	compareLayout string
+)
+
+// compareCmd represents the compare command
+var compareCmd = &cobra.Command{
+	Use:   "compare -m <model> -m <model>... <prompt>",
+	Short: "Ask several models the same prompt.",
+	Long: `Send the same prompt to several local models, up to four at the same time, and show their answers side by side,
+together with the latency and token stats of each model. Every model gets its own system prompt and options from the config.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 || len(compareModels) < 2 {
+			cmd.Help()
+			return
+		}
+		if !slices.Contains([]string{"auto", "columns", "sections"}, compareLayout) {
+			fmt.Println(Red("[Error]") + " --layout must be auto, columns or sections")
+			return
+		}
+
+		local, err := ollama.LocalModels()
+		if err != nil {
+			fmt.Println(Red("[Error] ") + err.Error())
+			return
+		}
+		var reqs []*ollama.Ollama
+		for _, m := range compareModels {
+			model := normalizeModel(m)
+			if !slices.Contains(local, model) {
+				fmt.Printf("%s Model %s not found, pull it with 'schlama pull %s'.\n", Red("[Error]"), model, model)
+				return
+			}
+			req, err := config.ReadConfigFor(model)
+			if err != nil {
+				fmt.Println(Red("[Error] ") + err.Error())
+				return
+			}
+			req.Messages = append(req.Messages, ollama.Message{Role: "user", Content: args[0]})
+			reqs = append(reqs, req)
+		}
+
+		fmt.Printf("%s Asking %d models...\n", Yellow("[Hint]"), len(reqs))
+		results := ollama.Compare(context.Background(), reqs, nil)
+
+		width, _, err := term.GetSize(int(os.Stdout.Fd()))
+		if err != nil || width <= 0 {
+			width = 100
+		}
+		layout := compareLayout
+		if layout == "auto" {
+			layout = "sections"
+			if width/len(results) >= 40 {
+				layout = "columns"
+			}
+		}
+		if layout == "columns" {
+			fmt.Println(compareColumns(results, width))
+			return
+		}
+		for _, r := range results {
+			fmt.Println(Cyan("== "+r.Model) + " (" + r.Stats() + ")")
+			if r.Err != nil {
+				fmt.Println(Red("[Error] ") + r.Err.Error())
+				continue
+			}
+			ollama.PrintMarkdown(r.Answer)
+		}
+	},
+}
+
+// compareColumns puts the answers next to each other, each in a column of
+// the same width.
+func compareColumns(results []ollama.Result, width int) string {
+	colWidth := width/len(results) - 1
+	style := lipgloss.NewStyle().Width(colWidth).PaddingRight(2)
+	var cols []string
+	for _, r := range results {
+		answer := r.Answer
+		if r.Err != nil {
+			answer = Red("[Error] ") + r.Err.Error()
+		}
+		col := Cyan(r.Model) + "\n(" + r.Stats() + ")\n" + strings.Repeat("-", colWidth-2) + "\n" + strings.TrimSpace(answer)
+		cols = append(cols, style.Render(col))
+	}
+	return lipgloss.JoinHorizontal(lipgloss.Top, cols...)
+}
+
+func init() {
+	compareCmd.Flags().StringArrayVarP(&compareModels, "model", "m", nil, "Model to ask, repeat for every model")
+	compareCmd.Flags().StringVar(&compareLayout, "layout", "auto", "Show the answers in columns or sections, auto picks columns if the terminal is wide enough")
+	rootCmd.AddCommand(compareCmd)
+}
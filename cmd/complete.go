@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"cmp"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/HanmaDevin/schlama/config"
+	"github.com/HanmaDevin/schlama/ollama"
+	"github.com/HanmaDevin/schlama/session"
+)
+
+// shellCompleter completes slash commands and their arguments.
+type shellCompleter struct{}
+
+// Do implements readline.AutoCompleter. It returns the candidates without
+// the part of the word that is already typed.
+func (shellCompleter) Do(line []rune, pos int) ([][]rune, int) {
+	typed := string(line[:pos])
+	if !strings.HasPrefix(typed, "/") || strings.HasPrefix(typed, "//") {
+		return nil, 0
+	}
+
+	name, rest, hasArgs := strings.Cut(typed[1:], " ")
+	if !hasArgs {
+		var names []string
+		for _, c := range slashCommands {
+			if strings.HasPrefix(c.name, name) {
+				names = append(names, c.name+" ")
+			}
+		}
+		return suffixes(names, name), len([]rune(name))
+	}
+
+	for _, c := range slashCommands {
+		if c.name != name || c.complete == nil {
+			continue
+		}
+		// complete the last word, words are separated by unescaped spaces
+		word := rest
+		for i := len(rest) - 1; i >= 0; i-- {
+			if rest[i] == ' ' && (i == 0 || rest[i-1] != '\\') {
+				word = rest[i+1:]
+				break
+			}
+		}
+		return suffixes(c.complete(word), word), len([]rune(word))
+	}
+	return nil, 0
+}
+
+// suffixes cuts typed from the start of every candidate.
+func suffixes(candidates []string, typed string) [][]rune {
+	var out [][]rune
+	for _, c := range candidates {
+		out = append(out, []rune(strings.TrimPrefix(c, typed)))
+	}
+	return out
+}
+
+// completePath completes file and directory names. Spaces in the candidates
+// are escaped so they stay one argument.
+func completePath(word string) []string {
+	path := strings.ReplaceAll(word, `\ `, " ")
+	dir, prefix := filepath.Split(path)
+	entries, err := os.ReadDir(expandPath(cmp.Or(dir, ".")))
+	if err != nil {
+		return nil
+	}
+
+	var candidates []string
+	for _, e := range entries {
+		name := e.Name()
+		if !strings.HasPrefix(name, prefix) || (strings.HasPrefix(name, ".") && !strings.HasPrefix(prefix, ".")) {
+			continue
+		}
+		candidate := strings.ReplaceAll(dir+name, " ", `\ `)
+		if e.IsDir() {
+			candidate += string(filepath.Separator)
+		} else {
+			candidate += " "
+		}
+		candidates = append(candidates, candidate)
+	}
+	return candidates
+}
+
+func completeModel(word string) []string {
+	models, err := ollama.LocalModels()
+	if err != nil {
+		return nil
+	}
+	var candidates []string
+	for _, m := range models {
+		if strings.HasPrefix(m, word) {
+			candidates = append(candidates, m)
+		}
+	}
+	slices.Sort(candidates)
+	return candidates
+}
+
+func completeSession(word string) []string {
+	sessions, err := session.List()
+	if err != nil {
+		return nil
+	}
+	var candidates []string
+	for _, s := range sessions {
+		if strings.HasPrefix(s.ID, word) {
+			candidates = append(candidates, s.ID)
+		}
+	}
+	return candidates
+}
+
+// historyFile returns the file the shell history is kept in.
+func historyFile() string {
+	return filepath.Join(config.Dir(), "history")
+}
@@ -0,0 +1,219 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/HanmaDevin/schlama/config"
+	"github.com/spf13/cobra"
+)
+
+// configCmd represents the config command
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Read and change settings.",
+	Long: `Read and change the settings in the config file.
+With --profile the command reads and changes the named profile instead of the top level settings.
+
+Every key can be overridden by an environment variable named SCHLAMA_ followed by the key in upper case
+with dots replaced by underscores, e.g. SCHLAMA_WEB_PORT or SCHLAMA_OPTIONS_TEMPERATURE.
+Precedence is flag > environment > profile > file > default, see 'schlama config explain'.
+
+Keys:
+` + config.KeyHelp(),
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Print the effective value of a setting.",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			cmd.Help()
+			return
+		}
+		cfg, err := config.Current()
+		if err != nil {
+			fmt.Println(Red("[Error] ") + err.Error())
+			return
+		}
+		value, _, err := cfg.Get(args[0])
+		if err != nil {
+			fmt.Println(Red("[Error] ") + err.Error())
+			return
+		}
+		fmt.Println(config.Display(args[0], value))
+	},
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Change a setting.",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 2 {
+			cmd.Help()
+			return
+		}
+		err := config.Update(func(layer *config.Config) error {
+			return layer.Set(args[0], args[1])
+		})
+		if err != nil {
+			fmt.Println(Red("[Error] ") + err.Error())
+			return
+		}
+		fmt.Printf("%s %s set to %s.\n", Green("[Msg]"), args[0], config.Display(args[0], args[1]))
+	},
+}
+
+var configUnsetCmd = &cobra.Command{
+	Use:   "unset <key>",
+	Short: "Remove a setting so the default applies again.",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			cmd.Help()
+			return
+		}
+		err := config.Update(func(layer *config.Config) error {
+			return layer.Unset(args[0])
+		})
+		if err != nil {
+			fmt.Println(Red("[Error] ") + err.Error())
+			return
+		}
+		fmt.Printf("%s %s unset.\n", Green("[Msg]"), args[0])
+	},
+}
+
+var configListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the effective settings.",
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, err := config.Current()
+		if err != nil {
+			fmt.Println(Red("[Error] ") + err.Error())
+			return
+		}
+		for _, key := range cfg.Keys() {
+			value, _, _ := cfg.Get(key)
+			fmt.Printf("%s = %s\n", Cyan(key), config.Display(key, value))
+		}
+	},
+}
+
+var configExplainCmd = &cobra.Command{
+	Use:   "explain",
+	Short: "Show where each effective setting comes from.",
+	Run: func(cmd *cobra.Command, args []string) {
+		settings, err := config.Explain()
+		if err != nil {
+			fmt.Println(Red("[Error] ") + err.Error())
+			return
+		}
+		if p := config.ActiveProfile(); p != "" {
+			fmt.Printf("%s Profile: %s\n", Green("[Msg]"), p)
+		}
+
+		var rows []string
+		header := fmt.Sprintf("%-32s %-30s %s", "KEY", "VALUE", "SOURCE")
+		rows = append(rows, header)
+		divider := fmt.Sprintf("%-32s %-30s %s", strings.Repeat("-", 32), strings.Repeat("-", 30), strings.Repeat("-", 30))
+		rows = append(rows, divider)
+		for _, s := range settings {
+			value := shorten(strings.ReplaceAll(config.Display(s.Key, s.Value), "\n", " "), 30)
+			rows = append(rows, fmt.Sprintf("%-32s %-30s %s", s.Key, value, s.Source))
+		}
+		fmt.Println(strings.Join(rows, "\n"))
+	},
+}
+
+var configEditCmd = &cobra.Command{
+	Use:   "edit",
+	Short: "Open the config file in your editor.",
+	Long: `Open a copy of the config file in $VISUAL or $EDITOR. The copy is validated after the editor
+is closed and only replaces the config file if it is valid.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		data, err := os.ReadFile(config.Path())
+		if os.IsNotExist(err) {
+			// write the defaults, so the editor doesn't start with an empty file
+			if err = config.Update(func(*config.Config) error { return nil }); err == nil {
+				data, err = os.ReadFile(config.Path())
+			}
+		}
+		if err != nil {
+			fmt.Println(Red("[Error]") + " Not able to read the config file: " + err.Error())
+			return
+		}
+
+		tmp, err := os.CreateTemp("", "schlama-config-*.yaml")
+		if err != nil {
+			fmt.Println(Red("[Error] ") + err.Error())
+			return
+		}
+		defer os.Remove(tmp.Name())
+		_, err = tmp.Write(data)
+		if cerr := tmp.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			fmt.Println(Red("[Error] ") + err.Error())
+			return
+		}
+
+		for {
+			if err := openEditor(tmp.Name()); err != nil {
+				fmt.Println(Red("[Error]") + " Not able to run the editor: " + err.Error())
+				return
+			}
+			edited, err := os.ReadFile(tmp.Name())
+			if err != nil {
+				fmt.Println(Red("[Error]") + " Not able to read the edited config: " + err.Error())
+				return
+			}
+			if err := config.ReplaceFile(edited); err != nil {
+				fmt.Println(Red("[Error] ") + err.Error())
+				if !confirm("Edit again?") {
+					fmt.Println(Yellow("[Hint]") + " Config not changed.")
+					return
+				}
+				continue
+			}
+			fmt.Println(Green("[Msg]") + " Config saved.")
+			return
+		}
+	},
+}
+
+// openEditor opens path in the user's editor and waits until it is closed.
+func openEditor(path string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+		if runtime.GOOS == "windows" {
+			editor = "notepad"
+		}
+	}
+	parts := strings.Fields(editor)
+	c := exec.Command(parts[0], append(parts[1:], path)...)
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	return c.Run()
+}
+
+func init() {
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(configUnsetCmd)
+	configCmd.AddCommand(configListCmd)
+	configCmd.AddCommand(configExplainCmd)
+	configCmd.AddCommand(configEditCmd)
+	rootCmd.AddCommand(configCmd)
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/HanmaDevin/schlama/ollama"
+	"github.com/spf13/cobra"
+)
+
+// cpCmd represents the cp command
+var cpCmd = &cobra.Command{
+	Use:   "cp <source> <destination>",
+	Short: "Copy a model.",
+	Long:  `Copy a model to a new name, e.g. to tag an internal variant before pushing it to a registry.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 2 {
+			cmd.Help()
+			return
+		}
+		src := normalizeModel(args[0])
+		dst := normalizeModel(args[1])
+		if src == dst {
+			fmt.Printf("%s Source and destination are both %s.\n", Red("[Error]"), src)
+			return
+		}
+
+		if !ollama.IsModelPresent(src) {
+			fmt.Printf("%s Model %s not found locally. Cannot copy a model that does not exist.\n", Red("[Error]"), src)
+			return
+		}
+		if err := ollama.CopyModel(src, dst); err != nil {
+			fmt.Println(Red("[Error] ") + err.Error())
+			return
+		}
+		fmt.Printf("%s Model %s copied to %s.\n", Green("[Msg]"), src, dst)
+	},
+}
+
+// mvCmd represents the mv command
+var mvCmd = &cobra.Command{
+	Use:   "mv <source> <destination>",
+	Short: "Rename a model.",
+	Long:  `Rename a model by copying it to the new name and removing the old one.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 2 {
+			cmd.Help()
+			return
+		}
+		src := normalizeModel(args[0])
+		dst := normalizeModel(args[1])
+		if src == dst {
+			fmt.Printf("%s Source and destination are both %s.\n", Red("[Error]"), src)
+			return
+		}
+
+		if !ollama.IsModelPresent(src) {
+			fmt.Printf("%s Model %s not found locally. Cannot rename a model that does not exist.\n", Red("[Error]"), src)
+			return
+		}
+		if err := ollama.CopyModel(src, dst); err != nil {
+			fmt.Println(Red("[Error] ") + err.Error())
+			return
+		}
+		if err := ollama.RemoveModel(src); err != nil {
+			fmt.Println(Red("[Error] ") + err.Error())
+			fmt.Printf("%s The copy %s was created, remove %s manually.\n", Yellow("[Hint]"), dst, src)
+			return
+		}
+		fmt.Printf("%s Model %s renamed to %s.\n", Green("[Msg]"), src, dst)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(cpCmd)
+	rootCmd.AddCommand(mvCmd)
+}
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/HanmaDevin/schlama/ollama"
+	"github.com/spf13/cobra"
+)
+
+var modelfile string
+var from string
+var system string
+var params []string
+
+// createCmd represents the create command
+var createCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Create a custom model.",
+	Long: `Create a custom model from a Modelfile, or let schlama build the Modelfile from flags.
+
+Examples:
+
+  schlama create assistant -f Modelfile
+  schlama create assistant --from llama3 --system "You are a helpful reviewer." --param temperature=0.2
+
+Flags given together with -f override the values of the Modelfile.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			cmd.Help()
+			return
+		}
+		name := normalizeModel(args[0])
+
+		mf := &ollama.Modelfile{}
+		if cmd.Flags().Changed("file") {
+			f, err := os.Open(modelfile)
+			if err != nil {
+				fmt.Println(Red("[Error]") + " Not able to read the specified Modelfile!")
+				return
+			}
+			defer f.Close()
+			mf, err = ollama.ParseModelfile(f)
+			if err != nil {
+				fmt.Println(Red("[Error]") + " Invalid Modelfile: " + err.Error())
+				return
+			}
+		} else if !cmd.Flags().Changed("from") {
+			fmt.Println(Red("[Error]") + " Please provide a Modelfile with -f or a base model with --from.")
+			return
+		}
+
+		if cmd.Flags().Changed("from") {
+			mf.From = normalizeModel(from)
+		}
+		if cmd.Flags().Changed("system") {
+			mf.System = system
+		}
+		for _, p := range params {
+			key, value, ok := strings.Cut(p, "=")
+			if !ok || key == "" {
+				fmt.Printf("%s Invalid parameter %q. Use --param name=value.\n", Red("[Error]"), p)
+				return
+			}
+			mf.SetParameter(key, value)
+		}
+
+		if !cmd.Flags().Changed("file") {
+			fmt.Println(Yellow("[Hint]") + " Using Modelfile:")
+			fmt.Println(mf.String())
+		}
+
+		if !ollama.IsModelPresent(normalizeModel(mf.From)) {
+			fmt.Printf("%s Base model %s not found locally. Pull it first using 'schlama pull %s'.\n", Red("[Error]"), mf.From, mf.From)
+			return
+		}
+
+		if err := ollama.CreateModel(name, mf); err != nil {
+			fmt.Println(Red("[Error] ") + err.Error())
+			return
+		}
+		fmt.Printf("%s Model %s created successfully.\n", Green("[Msg]"), name)
+	},
+}
+
+func init() {
+	createCmd.Flags().StringVarP(&modelfile, "file", "f", "", "Path to a Modelfile")
+	createCmd.Flags().StringVar(&from, "from", "", "Base model to build on")
+	createCmd.Flags().StringVar(&system, "system", "", "System prompt of the new model")
+	createCmd.Flags().StringArrayVar(&params, "param", nil, "Model parameter as name=value, can be repeated")
+	rootCmd.AddCommand(createCmd)
+}
@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/chzyer/readline"
+)
+
+// blockDelimiter starts and ends a prompt that spans several lines.
+const blockDelimiter = `"""`
+
+// pasteNewline stands in for a pasted line break while readline edits the
+// line, readline would submit the line on a real one.
+const pasteNewline = "↵"
+
+var (
+	pasteStart = []byte("\x1b[200~")
+	pasteEnd   = []byte("\x1b[201~")
+)
+
+// pasteReader strips the bracketed paste markers of the terminal and
+// replaces the line breaks between them with pasteNewline.
+type pasteReader struct {
+	r io.Reader
+	// out holds filtered bytes that were not read yet.
+	out []byte
+	// marker holds the start of what may be a paste marker.
+	marker []byte
+	paste  bool
+	cr     bool
+}
+
+func newPasteReader(r io.Reader) *pasteReader {
+	return &pasteReader{r: r}
+}
+
+func (p *pasteReader) Read(b []byte) (int, error) {
+	for len(p.out) == 0 {
+		chunk := make([]byte, len(b))
+		n, err := p.r.Read(chunk)
+		p.filter(chunk[:n])
+		if err != nil {
+			p.out = append(p.out, p.marker...)
+			p.marker = nil
+			if len(p.out) == 0 {
+				return 0, err
+			}
+		}
+	}
+	n := copy(b, p.out)
+	p.out = p.out[n:]
+	return n, nil
+}
+
+func (p *pasteReader) filter(data []byte) {
+	for _, c := range data {
+		if len(p.marker) == 0 && c != 0x1b {
+			p.emit(c)
+			continue
+		}
+
+		p.marker = append(p.marker, c)
+		switch {
+		case bytes.Equal(p.marker, pasteStart):
+			p.paste = true
+			p.marker = nil
+		case bytes.Equal(p.marker, pasteEnd):
+			p.paste = false
+			p.marker = nil
+		case bytes.HasPrefix(pasteStart, p.marker), bytes.HasPrefix(pasteEnd, p.marker):
+			// wait for the rest of the marker
+		case c == 0x1b:
+			// an escape sequence that is no marker, followed by a new one
+			p.out = append(p.out, p.marker[:len(p.marker)-1]...)
+			p.marker = []byte{c}
+		default:
+			p.out = append(p.out, p.marker...)
+			p.marker = nil
+		}
+	}
+}
+
+func (p *pasteReader) emit(c byte) {
+	if !p.paste || (c != '\r' && c != '\n') {
+		p.cr = false
+		p.out = append(p.out, c)
+		return
+	}
+	// terminals paste \r, \n or \r\n, all of them are one line break
+	if c == '\n' && p.cr {
+		p.cr = false
+		return
+	}
+	p.cr = c == '\r'
+	p.out = append(p.out, pasteNewline...)
+}
+
+// enableBracketedPaste asks the terminal to mark pasted text and returns a
+// function that turns it off again.
+func enableBracketedPaste() func() {
+	if runtime.GOOS == "windows" || !readline.IsTerminal(int(os.Stdout.Fd())) {
+		return func() {}
+	}
+	os.Stdout.WriteString("\x1b[?2004h")
+	return func() {
+		os.Stdout.WriteString("\x1b[?2004l")
+	}
+}
+
+// readPrompt reads the next input of the shell. Pasted text and everything
+// between two """ lines is read as one prompt.
+func readPrompt(l *readline.Instance) (string, error) {
+	line, err := l.Readline()
+	if err != nil {
+		return "", err
+	}
+	line = strings.ReplaceAll(line, pasteNewline, "\n")
+
+	text, ok := strings.CutPrefix(strings.TrimSpace(line), blockDelimiter)
+	if !ok {
+		return line, nil
+	}
+	if block, ok := strings.CutSuffix(text, blockDelimiter); ok {
+		return block, nil
+	}
+
+	prompt := l.Config.Prompt
+	defer l.SetPrompt(prompt)
+	l.SetPrompt(Cyan("... "))
+
+	lines := []string{text}
+	for {
+		line, err := l.Readline()
+		if err != nil {
+			// an interrupt only drops the block, not the session
+			if err == readline.ErrInterrupt {
+				return "", nil
+			}
+			return "", err
+		}
+		line = strings.ReplaceAll(line, pasteNewline, "\n")
+		if rest, ok := strings.CutSuffix(strings.TrimRight(line, " \t"), blockDelimiter); ok {
+			lines = append(lines, rest)
+			return strings.Trim(strings.Join(lines, "\n"), "\n"), nil
+		}
+		lines = append(lines, line)
+	}
+}
+
+// editText opens text in the user's editor and returns what was saved.
+func editText(text string) (string, error) {
+	f, err := os.CreateTemp("", "schlama-*.md")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(text); err != nil {
+		f.Close()
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+
+	if err := openEditor(f.Name()); err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(f.Name())
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestPasteReader(t *testing.T) {
+	const start, end = "\x1b[200~", "\x1b[201~"
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"plain input", "hello\n", "hello\n"},
+		{"paste", start + "a\nb" + end + "\n", "a" + pasteNewline + "b\n"},
+		{"carriage returns", start + "a\rb\r\nc" + end + "\r", "a" + pasteNewline + "b" + pasteNewline + "c\r"},
+		{"empty lines in a paste", start + "a\n\nb" + end, "a" + pasteNewline + pasteNewline + "b"},
+		{"line breaks outside a paste", "a\n" + start + "b" + end + "\nc\n", "a\nb\nc\n"},
+		{"other escape sequences", "\x1b[A\x1b[2~x", "\x1b[A\x1b[2~x"},
+		{"escape right before a marker", "\x1b" + start + "a\nb" + end, "\x1ba" + pasteNewline + "b"},
+		{"unfinished marker at the end", "a\x1b[20", "a\x1b[20"},
+		{"lone end marker", "a" + end + "\nb", "a\nb"},
+	}
+	for _, tt := range tests {
+		readers := map[string]io.Reader{
+			"whole":    strings.NewReader(tt.input),
+			"one byte": iotest.OneByteReader(strings.NewReader(tt.input)),
+			"half":     iotest.HalfReader(strings.NewReader(tt.input)),
+		}
+		for kind, r := range readers {
+			t.Run(tt.name+"/"+kind, func(t *testing.T) {
+				got, err := io.ReadAll(newPasteReader(r))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(got) != tt.want {
+					t.Errorf("read %q, want %q", got, tt.want)
+				}
+			})
+		}
+	}
+}
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/HanmaDevin/schlama/ollama"
 	"github.com/spf13/cobra"
@@ -17,7 +18,16 @@ var listCmd = &cobra.Command{
 	Long:  `List gets all the available models from ollama.com and displays them.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		if local {
-			ollama.ListLocalModels()
+			models, err := ollama.InstalledModels()
+			if err != nil {
+				fmt.Println(Red("[Error] ") + err.Error())
+				return
+			}
+			if len(models) == 0 {
+				fmt.Println(Yellow("[Hint]") + " No models found!")
+				return
+			}
+			fmt.Println(createLocalTable(models))
 			return
 		}
 		models := ollama.ListModels()
@@ -26,6 +36,27 @@ var listCmd = &cobra.Command{
 	},
 }
 
+func createLocalTable(models []ollama.LocalModel) string {
+	var rows []string
+	header := fmt.Sprintf("%-40s %-14s %-10s %-20s", "NAME", "ID", "SIZE", "MODIFIED")
+	rows = append(rows, header)
+	divider := fmt.Sprintf("%-40s %-14s %-10s %-20s", strings.Repeat("-", 40), strings.Repeat("-", 14), strings.Repeat("-", 10), strings.Repeat("-", 20))
+	rows = append(rows, divider)
+	for _, m := range models {
+		line := fmt.Sprintf("%-40s %-14s %-10s %-20s", m.Name, shortDigest(m.Digest), formatBytes(m.Size), m.ModifiedAt.Local().Format("2006-01-02 15:04"))
+		rows = append(rows, line)
+	}
+	return strings.Join(rows, "\n")
+}
+
+// shortDigest shortens a digest to the id that ollama shows.
+func shortDigest(digest string) string {
+	if len(digest) > 12 {
+		return digest[:12]
+	}
+	return digest
+}
+
 func init() {
 	listCmd.Flags().IntVarP(&limit, "limit", "l", 25, "Limit the output.")
 	listCmd.Flags().BoolVar(&local, "local", false, "List local models.")
@@ -13,7 +13,11 @@ var modelCmd = &cobra.Command{
 	Short: "Show the currently selected model.",
 	Long:  `Show the currently selected model.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		body := config.ReadConfig()
+		body, err := config.ReadConfig()
+		if err != nil {
+			fmt.Println(Red("[Error] ") + err.Error())
+			return
+		}
 		out := fmt.Sprintf(Green("[Msg]")+" Current Model: %s", body.Model)
 		fmt.Println(out)
 	},
@@ -16,6 +16,7 @@ import (
 var file string
 var directory string
 var images []string
+var keepAlive string
 
 // promptCmd represents the prompt command
 var promptCmd = &cobra.Command{
@@ -26,16 +27,29 @@ var promptCmd = &cobra.Command{
 		if len(args) != 1 {
 			cmd.Help()
 		} else {
-			body := config.ReadConfig()
+			if cmd.Flags().Changed("keep-alive") {
+				if err := config.SetFlag("keep_alive", keepAlive, "--keep-alive"); err != nil {
+					fmt.Println(Red("[Error] ") + err.Error())
+					return
+				}
+			}
+
+			body, err := config.ReadConfig()
+			if err != nil {
+				fmt.Println(Red("[Error] ") + err.Error())
+				return
+			}
 			if body.Model == "" {
 				fmt.Println(Yellow("[Hint]") + " No model selected. Please set a model using 'schlama select <model_name>'.")
 				return
 			}
 
-			body.Messages[0].Content = args[0]
+			msg := ollama.Message{
+				Role:    "user",
+				Content: args[0],
+			}
 
 			var f []byte
-			var err error
 			if cmd.Flags().Changed("file") {
 				fmt.Println(Yellow("[Hint]") + " Reading file: " + file)
 				f, err = os.ReadFile(file)
@@ -43,7 +57,7 @@ var promptCmd = &cobra.Command{
 					fmt.Println(Red("[Error]") + " Not able to read the specified file!")
 					os.Exit(1)
 				}
-				body.Messages[0].Content += "\n" + string(f)
+				msg.Content += "\n" + string(f)
 			}
 
 			if cmd.Flags().Changed("directory") {
@@ -53,7 +67,7 @@ var promptCmd = &cobra.Command{
 					fmt.Println(Red("[Error]") + " Not able to read the specified directory!")
 					os.Exit(1)
 				}
-				body.Messages[0].Content += "\n" + data
+				msg.Content += "\n" + data
 			}
 
 			if cmd.Flags().Changed("images") {
@@ -64,10 +78,11 @@ var promptCmd = &cobra.Command{
 						fmt.Println(Red("[Error]") + " Not able to read the specified image!")
 						os.Exit(1)
 					}
-					body.Messages[0].Images = append(body.Messages[0].Images, encoded)
+					msg.Images = append(msg.Images, encoded)
 				}
 			}
 
+			body.Messages = append(body.Messages, msg)
 			resp, err := ollama.GetResponse(body)
 			if err != nil {
 				fmt.Println(err.Error())
@@ -117,5 +132,6 @@ func init() {
 	promptCmd.Flags().StringVarP(&file, "file", "f", "", "Prompt with file content")
 	promptCmd.Flags().StringVarP(&directory, "directory", "d", "", "Prompt with directory content")
 	promptCmd.Flags().StringSliceVarP(&images, "images", "i", nil, "Prompt with image content")
+	promptCmd.Flags().StringVar(&keepAlive, "keep-alive", "", "How long the model stays loaded after the prompt, e.g. 5m, 1h or -1 for forever")
 	rootCmd.AddCommand(promptCmd)
 }
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/HanmaDevin/schlama/ollama"
+	"github.com/spf13/cobra"
+)
+
+// psCmd represents the ps command
+var psCmd = &cobra.Command{
+	Use:   "ps",
+	Short: "Show running models.",
+	Long:  `Show the models that are loaded into memory, how much VRAM they use and when they will be unloaded.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		models, err := ollama.RunningModels()
+		if err != nil {
+			fmt.Println(Red("[Error] ") + err.Error())
+			return
+		}
+		if len(models) == 0 {
+			fmt.Println(Yellow("[Hint]") + " No models are loaded.")
+			return
+		}
+		fmt.Println(createPsTable(models))
+	},
+}
+
+// stopCmd represents the stop command
+var stopCmd = &cobra.Command{
+	Use:   "stop <model>",
+	Short: "Unload a running model.",
+	Long:  `Unload a model from memory right away instead of waiting for its keep_alive to expire.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			cmd.Help()
+			return
+		}
+		model := normalizeModel(args[0])
+
+		if err := ollama.StopModel(model); err != nil {
+			fmt.Println(Red("[Error] ") + err.Error())
+			return
+		}
+		fmt.Printf("%s Model %s unloaded.\n", Green("[Msg]"), model)
+	},
+}
+
+func createPsTable(models []ollama.RunningModel) string {
+	var rows []string
+	header := fmt.Sprintf("%-30s %-10s %-10s %-16s %-20s", "NAME", "SIZE", "VRAM", "PROCESSOR", "UNTIL")
+	rows = append(rows, header)
+	divider := fmt.Sprintf("%-30s %-10s %-10s %-16s %-20s", strings.Repeat("-", 30), strings.Repeat("-", 10), strings.Repeat("-", 10), strings.Repeat("-", 16), strings.Repeat("-", 20))
+	rows = append(rows, divider)
+	for _, m := range models {
+		line := fmt.Sprintf("%-30s %-10s %-10s %-16s %-20s", m.Name, formatBytes(m.Size), formatBytes(m.SizeVRAM), processor(m), until(m.ExpiresAt))
+		rows = append(rows, line)
+	}
+	return strings.Join(rows, "\n")
+}
+
+// processor tells how a model is split between CPU and GPU memory.
+func processor(m ollama.RunningModel) string {
+	if m.Size == 0 {
+		return "-"
+	}
+	gpu := m.SizeVRAM * 100 / m.Size
+	switch gpu {
+	case 0:
+		return "100% CPU"
+	case 100:
+		return "100% GPU"
+	default:
+		return fmt.Sprintf("%d%%/%d%% CPU/GPU", 100-gpu, gpu)
+	}
+}
+
+func until(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	d := time.Until(t)
+	switch {
+	case d > 24*365*time.Hour:
+		return "forever"
+	case d <= 0:
+		return "now"
+	default:
+		return d.Round(time.Second).String()
+	}
+}
+
+func formatBytes(b int64) string {
+	const unit = 1000
+	if b < unit {
+		return fmt.Sprintf("%d B", b)
+	}
+	div, exp := int64(unit), 0
+	for n := b / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(b)/float64(div), "KMGTPE"[exp])
+}
+
+func init() {
+	rootCmd.AddCommand(psCmd)
+	rootCmd.AddCommand(stopCmd)
+}
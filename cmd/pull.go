@@ -2,7 +2,6 @@ package cmd
 
 import (
 	"fmt"
-	"regexp"
 
 	"github.com/HanmaDevin/schlama/config"
 	"github.com/HanmaDevin/schlama/ollama"
@@ -10,49 +9,54 @@ import (
 )
 
 var pullCmd = &cobra.Command{
-	Use:   "pull",
-	Short: "Pull a model.",
-	Long:  `This command pulls a model from the Ollama server. If the model is already present, it will do nothing.`,
+	Use:   "pull <model>...",
+	Short: "Pull one or more models.",
+	Long:  `This command pulls models from the Ollama server. Models that are already present are skipped. When a single model is pulled it also becomes the current model.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		if len(args) != 1 {
+		if len(args) < 1 {
 			cmd.Help()
-		} else {
-			// Check if the model is present in the local models
-			nameReg := regexp.MustCompile(`[\w\-]+\d?\.?\d?`)
-			name := nameReg.FindString(args[0])
-
-			labelReg := regexp.MustCompile(`:\w+\.?(\w+)?`)
-			label := labelReg.FindString(args[0])
-			if label == "" {
-				label = ":latest"
+			return
+		}
+
+		var missing []string
+		for _, arg := range args {
+			model := normalizeModel(arg)
+			if ollama.IsModelPresent(model) {
+				fmt.Printf("%s Model %s already present locally. No need to pull again.\n", Yellow("[Hint]"), model)
+				continue
 			}
+			fmt.Printf("%s Model not found locally. Pulling model %s...\n", Yellow("[Hint]"), model)
+			missing = append(missing, model)
+		}
+		if len(missing) == 0 {
+			return
+		}
 
-			model := name + label
-
-			if !ollama.IsModelPresent(model) {
-				fmt.Printf("%s Model not found locally. Pulling model %s...\n", Yellow("[Hint]"), model)
-				err := ollama.PullModel(model)
-				if err != nil {
-					fmt.Println(Red("[Error] ") + err.Error())
-					fmt.Println(Yellow("[Hint]") + " Here is a short list of available models:")
-					models := ollama.ListModels()
-					table := ollama.CreateTable(models, 25)
-					fmt.Println(table)
-					return
-				}
-				fmt.Printf("%s %s pulled successfully.\n", Yellow("[Hint]"), model)
-
-				cfg := config.Config{
-					Model: model,
-				}
-				config.WriteConfig(cfg)
-				out := fmt.Sprintf("%s Current Model: %s", Green("[Msg]"), cfg.Model)
-				fmt.Println(out)
-				return
-			} else {
-				fmt.Println(Yellow("[Hint]") + " Model already present locally. No need to pull again.")
-				return
+		failed := false
+		for i, err := range ollama.PullModels(missing) {
+			if err != nil {
+				failed = true
+				fmt.Println(Red("[Error] ") + err.Error())
+				continue
 			}
+			fmt.Printf("%s %s pulled successfully.\n", Yellow("[Hint]"), missing[i])
+		}
+
+		if failed {
+			fmt.Println(Yellow("[Hint]") + " Here is a short list of available models:")
+			models := ollama.ListModels()
+			table := ollama.CreateTable(models, 25)
+			fmt.Println(table)
+			return
+		}
+
+		if len(args) == 1 {
+			config.Update(func(layer *config.Config) error {
+				layer.Model = missing[0]
+				return nil
+			})
+			out := fmt.Sprintf("%s Current Model: %s", Green("[Msg]"), missing[0])
+			fmt.Println(out)
 		}
 	},
 }
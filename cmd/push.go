@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/HanmaDevin/schlama/ollama"
+	"github.com/spf13/cobra"
+)
+
+var insecure bool
+
+// pushCmd represents the push command
+var pushCmd = &cobra.Command{
+	Use:   "push <model>",
+	Short: "Push a model to a registry.",
+	Long: `Push a model to the registry in its name, e.g. 'registry.local:5000/team/assistant'.
+Use 'schlama cp' first to give a local model a registry name.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			cmd.Help()
+			return
+		}
+		model := normalizeModel(args[0])
+
+		if !ollama.IsModelPresent(model) {
+			fmt.Printf("%s Model %s not found locally. Cannot push a model that does not exist.\n", Red("[Error]"), model)
+			return
+		}
+		if err := ollama.PushModel(model, insecure); err != nil {
+			fmt.Println(Red("[Error] ") + err.Error())
+			return
+		}
+		fmt.Printf("%s Model %s pushed successfully.\n", Green("[Msg]"), model)
+	},
+}
+
+func init() {
+	pushCmd.Flags().BoolVar(&insecure, "insecure", false, "Allow pushing to a registry over plain http.")
+	rootCmd.AddCommand(pushCmd)
+}
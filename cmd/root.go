@@ -3,7 +3,7 @@ package cmd
 import (
 	"fmt"
 	"os"
-	"path/filepath"
+	"strings"
 
 	"github.com/HanmaDevin/schlama/config"
 	"github.com/HanmaDevin/schlama/ollama"
@@ -16,11 +16,60 @@ var Red = color.New(color.FgRed).SprintFunc()
 var Yellow = color.New(color.FgYellow).SprintFunc()
 var Cyan = color.New(color.FgCyan).SprintFunc()
 
+// normalizeModel turns user input like "llama3" into the full model name
+// "llama3:latest" that ollama uses for local models.
+func normalizeModel(arg string) string {
+	return ollama.FullName(strings.TrimSpace(arg))
+}
+
+// shorten cuts s to at most n characters for tables, the end is replaced by
+// "..." then.
+func shorten(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n-3]) + "..."
+	}
+	return s
+}
+
+var profile string
+var configPath string
+var host string
+
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "schlama",
 	Short: "A better ollama user interface.",
 	Long:  `Schlama is a CLI and a web-chat app, depending on what you perfer, which allows for easy communication with local LLMs. It allows file/directory input and images are also supported (Only works with multimodal models). Basically an easier way to chat with local LLMs and install new ones.`,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		config.SetPath(configPath)
+		config.SetProfile(profile)
+		if cmd.Flags().Changed("host") {
+			if err := config.SetFlag("host", host, "--host"); err != nil {
+				fmt.Println(Red("[Error] ") + err.Error())
+				os.Exit(1)
+			}
+		}
+		// the config commands create and edit profiles and must work without
+		// ollama, just like reading stored conversations
+		if cmd.HasParent() && (cmd.Parent() == configCmd || cmd.Parent() == sessionsCmd) {
+			return
+		}
+
+		settings, err := config.Current()
+		if err != nil {
+			fmt.Println(Red("[Error] ") + err.Error())
+			os.Exit(1)
+		}
+		if p := config.ActiveProfile(); p != "" {
+			if ok, _ := config.HasProfile(p); !ok {
+				fmt.Printf("%s Profile %s does not exist.\n", Red("[Error]"), p)
+				fmt.Printf("%s Create it with 'schlama --profile %s config set <key> <value>'.\n", Yellow("[Hint]"), p)
+				os.Exit(1)
+			}
+		}
+		ollama.SetHost(settings.Host)
+		checkOllama()
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 		if len(args) == 0 {
 			cmd.Help()
@@ -38,23 +87,13 @@ func Execute() {
 }
 
 func init() {
-	if ollama.IsOllamaRunning() {
-		var home, _ = os.UserHomeDir()
-		var config_Path string = filepath.Dir(home + "/.config/schlama/")
-		if _, err := os.Stat(config_Path); os.IsNotExist(err) {
-			err := os.MkdirAll(config_Path, 0755)
-			if err != nil {
-				fmt.Println(Red("[Error] ") + "Creating config directory: '~/.config/schlama/config.yaml' did not work!")
-				os.Exit(-1)
-			}
-		}
+	rootCmd.PersistentFlags().StringVar(&profile, "profile", "", "Use the settings of a named config profile (default $SCHLAMA_PROFILE)")
+	rootCmd.PersistentFlags().StringVar(&host, "host", "", "Address of the ollama server")
+	rootCmd.PersistentFlags().StringVar(&configPath, "config", "", "Path of the config file (default $SCHLAMA_CONFIG or $XDG_CONFIG_HOME/schlama/config.yaml)")
+}
 
-		if _, err := os.Stat(config_Path + "/config.yaml"); os.IsNotExist(err) {
-			config.WriteConfig(config.Config{
-				Model: "",
-			})
-		}
-	} else {
+func checkOllama() {
+	if !ollama.IsOllamaRunning() {
 		fmt.Println(Red("[Error] ") + "Ollama is not running.")
 		fmt.Println(Red("[Error]") + " Please start ollama first.")
 		fmt.Println(Yellow("[Hint]") + " You can start ollama with the command: 'ollama serve'")
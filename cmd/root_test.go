@@ -0,0 +1,48 @@
+package cmd
+
+import "testing"
+
+func TestNormalizeModel(t *testing.T) {
+	tests := []struct {
+		arg  string
+		want string
+	}{
+		{"llama3", "llama3:latest"},
+		{" llama3 ", "llama3:latest"},
+		{"llama3:latest", "llama3:latest"},
+		{"llama3.1", "llama3.1:latest"},
+		{"llama3.1:8b", "llama3.1:8b"},
+		{"qwen2.5-coder:7b", "qwen2.5-coder:7b"},
+		{"qwen2.5-coder", "qwen2.5-coder:latest"},
+		{"deepseek-r1:1.5b", "deepseek-r1:1.5b"},
+		{"phi3:3.8b-mini-4k-instruct-q4_K_M", "phi3:3.8b-mini-4k-instruct-q4_K_M"},
+		{"nomic-embed-text", "nomic-embed-text:latest"},
+		{"library/llama3", "library/llama3:latest"},
+		{"hf.co/bartowski/Llama-3.2-1B-Instruct-GGUF:Q4_K_M", "hf.co/bartowski/Llama-3.2-1B-Instruct-GGUF:Q4_K_M"},
+		{"registry.local:5000/team/llama3", "registry.local:5000/team/llama3:latest"},
+	}
+	for _, tt := range tests {
+		if got := normalizeModel(tt.arg); got != tt.want {
+			t.Errorf("normalizeModel(%q) = %q, want %q", tt.arg, got, tt.want)
+		}
+	}
+}
+
+func TestShorten(t *testing.T) {
+	tests := []struct {
+		s    string
+		n    int
+		want string
+	}{
+		{"short", 10, "short"},
+		{"exactly10!", 10, "exactly10!"},
+		{"a bit too long", 10, "a bit t..."},
+		{"ääääääääääää", 10, "äääääää..."},
+		{"日本語のタイトルです長い", 8, "日本語のタ..."},
+	}
+	for _, tt := range tests {
+		if got := shorten(tt.s, tt.n); got != tt.want {
+			t.Errorf("shorten(%q, %d) = %q, want %q", tt.s, tt.n, got, tt.want)
+		}
+	}
+}
@@ -1,12 +1,14 @@
 package cmd
 
 import (
+	"io"
 	"os"
-	"regexp"
+	"path/filepath"
 	"strings"
 
 	"github.com/HanmaDevin/schlama/config"
 	"github.com/HanmaDevin/schlama/ollama"
+	"github.com/HanmaDevin/schlama/session"
 	"github.com/chzyer/readline"
 	"github.com/spf13/cobra"
 )
@@ -21,18 +23,29 @@ var runCmd = &cobra.Command{
 			cmd.Println(Red(">>> [Error]") + " Please provide a model as an argument.")
 			return
 		} else {
+			if cmd.Flags().Changed("keep-alive") {
+				if err := config.SetFlag("keep_alive", keepAlive, "--keep-alive"); err != nil {
+					cmd.Println(Red(">>> [Error] ") + err.Error())
+					return
+				}
+			}
 			runInteractiveShell(args[0])
 		}
 	},
 }
 
 func runInteractiveShell(model string) {
-	cfg := config.ReadConfig()
-
+	// without the directory readline silently keeps no history
+	if err := os.MkdirAll(filepath.Dir(historyFile()), 0755); err != nil {
+		println(Red(">>> [Error]")+" Not able to create the history file:", err.Error())
+	}
 	l, err := readline.NewEx(&readline.Config{
 		Prompt:          Cyan(">>> "),
 		InterruptPrompt: "^C",
 		EOFPrompt:       "exit",
+		HistoryFile:     historyFile(),
+		AutoComplete:    shellCompleter{},
+		Stdin:           readline.NewCancelableStdin(newPasteReader(readline.Stdin)),
 	})
 	if err != nil {
 		println(Red(">>> [Error]")+"Failed to create readline instance:", err.Error())
@@ -40,18 +53,9 @@ func runInteractiveShell(model string) {
 	}
 	defer l.Close()
 	l.CaptureExitSignal()
+	defer enableBracketedPaste()()
 
-	// Check if the model is present in the local models
-	nameReg := regexp.MustCompile(`[\w\-]+\d?\.?\d?`)
-	name := nameReg.FindString(model)
-
-	labelReg := regexp.MustCompile(`:\w+\.?(\w+)?`)
-	label := labelReg.FindString(model)
-	if label == "" {
-		label = ":latest"
-	}
-
-	model = name + label
+	model = normalizeModel(model)
 
 	for !ollama.IsModelPresent(model) {
 		println(Red(">>> [Error]") + "Model not found. Please ensure the model is downloaded and available locally")
@@ -61,106 +65,57 @@ func runInteractiveShell(model string) {
 			return
 		}
 
-		nameReg := regexp.MustCompile(`[\w\-]+\d?\.?\d?`)
-		name := nameReg.FindString(line)
-
-		labelReg := regexp.MustCompile(`:\w+\.?(\w+)?`)
-		label := labelReg.FindString(line)
-		if label == "" {
-			label = ":latest"
-		}
-
-		model = name + label
+		model = normalizeModel(line)
 	}
-	cfg.Model = model
-	msg := ollama.Message{
-		Role: "user",
+	sh, err := newShell(model)
+	if err != nil {
+		println(Red(">>> [Error]")+" Failed to read config:", err.Error())
+		return
 	}
 
-	println(Yellow(">>> [Hint]") + " Type 'help' or '?' for available flags.")
+	println(Yellow(">>> [Hint]") + " Type '/help' or '?' for available commands.")
+	println(Yellow(">>> [Hint]") + ` Use """ to start and end a prompt with several lines, or /edit to write it in your editor.`)
+	println(Yellow(">>> [Hint]") + " This conversation is stored as session " + sh.sess.ID + ".")
 	println(Cyan(">>>")+" Hello, how can I assist you:", model)
 
 	for {
-		line, err := l.Readline()
-		if err == readline.ErrInterrupt || line == "exit" {
+		line, err := readPrompt(l)
+		if err == readline.ErrInterrupt || err == io.EOF || line == "exit" {
 			println(Green(">>> [Msg]") + " Exiting interactive shell session.")
 			return
 		}
+		line = strings.TrimSpace(line)
 		if len(line) == 0 {
 			continue
 		}
-
 		if line == "help" || line == "?" {
-			println(Yellow(">>>") + " Ask something, or use the following commands:")
-			println(Yellow(">>>") + " --file <path> - Read the content of a file")
-			println(Yellow(">>>") + " --directory <path> - Read the content of a directory")
-			println(Yellow(">>>") + " --images <path> - Read the content of images")
-			println(Yellow(">>>") + " Type 'exit' to exit the interactive shell session.")
-			continue
+			line = "/help"
 		}
 
-		if strings.Contains(line, "--") {
-			parts := strings.Split(line, "--")
-			msg.Content = parts[0]
-			path := strings.Split(parts[1], " ")
-			if len(path) <= 1 {
-				println(Red(">>> [Error]") + " Please provide a valid path after the command.")
-				println(Yellow(">>> [Hint]") + " Use 'help' or '?' to see available commands.")
-				continue
-			}
-			switch path[0] {
-			case "file":
-				println(Yellow(">>> [Hint]")+" Reading file:", path[1])
-				file, err := os.ReadFile(path[1])
-				if err != nil {
-					println(Red(">>> [Error]")+" Not able to read the specified file:", err.Error())
-					continue
-				}
-				msg.Content += "\n" + string(file)
-			case "directory":
-				println(Yellow(">>> [Hint]")+" Reading directory:", path[1])
-				data, err := GetDirContent(path[1])
-				if err != nil {
-					println(Red(">>> [Error]")+" Not able to read the specified directory:", err.Error())
-					continue
-				}
-				msg.Content += "\n" + data
-			case "images":
-				println(Yellow(">>> [Hint]")+" Reading images:", path[1])
-				encoded, err := EncodeImageToBase64(path[1])
-				if err != nil {
-					println(Red(">>> [Error]")+" Not able to read the specified image:", err.Error())
-					continue
-				}
-				msg.Images = append(msg.Images, encoded)
-			default:
-				println(Red(">>> [Error]")+" Unknown command:", path[0])
-				println(Yellow(">>> [Hint]") + " Use 'help' or '?' to see available commands.")
-				continue
+		if err := sh.handle(line); err != nil {
+			if err == errExit {
+				println(Green(">>> [Msg]") + " Exiting interactive shell session.")
+				return
 			}
-		} else {
-			msg.Content = line
+			println(Red(">>> [Error]") + " " + err.Error())
 		}
-
-		// building up context
-		cfg.Messages = append(cfg.Messages, msg)
-		resp, err := ollama.GetResponse(cfg)
-		if err != nil {
-			println(Red(">>> [Error]")+" Failed to get response from Ollama:", err.Error())
-			continue
-		}
-
-		// building up context
-		cfg.Messages = append(cfg.Messages, ollama.Message{
-			Role:    "assistant",
-			Content: resp,
-		})
-
-		println("\n" + resp)
 	}
+}
 
+// exportSession renders sess to path in the format given by its extension.
+func exportSession(sess *session.Session, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := session.Render(f, sess, session.FormatFromPath(path)); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
 }
 
 func init() {
+	runCmd.Flags().StringVar(&keepAlive, "keep-alive", "", "How long the model stays loaded between prompts, e.g. 5m, 1h or -1 for forever")
 	rootCmd.AddCommand(runCmd)
 }
@@ -2,7 +2,6 @@ package cmd
 
 import (
 	"fmt"
-	"regexp"
 
 	"github.com/HanmaDevin/schlama/config"
 	"github.com/HanmaDevin/schlama/ollama"
@@ -18,28 +17,22 @@ var selectCmd = &cobra.Command{
 		if len(args) != 1 {
 			cmd.Help()
 		} else {
-			// Check if the model is present in the local models
-			nameReg := regexp.MustCompile(`[\w\-]+\d?\.?\d?`)
-			name := nameReg.FindString(args[0])
-
-			labelReg := regexp.MustCompile(`:\w+\.?(\w+)?`)
-			label := labelReg.FindString(args[0])
-			if label == "" {
-				label = ":latest"
-			}
-
-			model := name + label
+			model := normalizeModel(args[0])
 
 			if !ollama.IsModelPresent(model) {
 				fmt.Println(Red("[Error]") + " Model not found. Make sure to pull the model first using 'schlama pull <model_name>' command.")
 				return
 			}
 
-			cfg := config.Config{
-				Model: model,
+			err := config.Update(func(layer *config.Config) error {
+				layer.Model = model
+				return nil
+			})
+			if err != nil {
+				fmt.Println(Red("[Error]") + " Not able to write the config file: " + err.Error())
+				return
 			}
-			config.WriteConfig(cfg)
-			out := fmt.Sprintf("%s Current Model: %s", Green("[Msg]"), cfg.Model)
+			out := fmt.Sprintf("%s Current Model: %s", Green("[Msg]"), model)
 			fmt.Println(out)
 		}
 	},
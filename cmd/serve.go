@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"os"
+
+	"github.com/HanmaDevin/schlama/openai"
+	"github.com/HanmaDevin/schlama/server"
+	"github.com/spf13/cobra"
+)
+
+var (
+	serveOpenAI bool
+	serveAddr   string
+	servePort   int
+	serveAPIKey string
+)
+
+// serveCmd represents the serve command
+var serveCmd = &cobra.Command{
+	Use:   "serve --openai",
+	Short: "Serve an API for other tools.",
+	Long: `Serve an OpenAI compatible API with /v1/chat/completions, /v1/models and /v1/embeddings, streaming included.
+Requests are answered by ollama with the system prompt, options and keep alive of the config applied to each model,
+so tools that speak the OpenAI API get the same behavior as schlama. A system message or a parameter like temperature
+in the request wins over the config. Use --profile to serve the settings of a profile.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if !serveOpenAI {
+			cmd.Help()
+			return
+		}
+		key := serveAPIKey
+		if key == "" {
+			key = os.Getenv("SCHLAMA_API_KEY")
+		}
+
+		ln, err := server.Listen(serveAddr, servePort)
+		if err != nil {
+			fmt.Println(Red("[Error] ") + err.Error())
+			os.Exit(1)
+		}
+		addr := ln.Addr().(*net.TCPAddr)
+		fmt.Printf("%s OpenAI compatible API at %s/v1\n", Green("[Msg]"), server.URL(addr))
+		if key == "" && !addr.IP.IsLoopback() {
+			fmt.Println(Yellow("[Hint]") + " Everyone who can reach the API can use it, set an API key with --api-key.")
+		}
+		if err := server.Run(server.New(openai.Handler(key)), ln); err != nil {
+			fmt.Println(Red("[Error] ") + err.Error())
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	serveCmd.Flags().BoolVar(&serveOpenAI, "openai", false, "Serve the OpenAI compatible API")
+	serveCmd.Flags().StringVar(&serveAddr, "addr", "localhost", "Address to listen on, use 0.0.0.0 to allow other machines")
+	serveCmd.Flags().IntVarP(&servePort, "port", "p", 11435, "Port to listen on")
+	serveCmd.Flags().StringVar(&serveAPIKey, "api-key", "", "Key clients have to send as bearer token (default $SCHLAMA_API_KEY)")
+	rootCmd.AddCommand(serveCmd)
+}
@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/HanmaDevin/schlama/session"
+	"github.com/spf13/cobra"
+)
+
+var (
+	exportFormat string
+	exportOutput string
+)
+
+// sessionsCmd represents the sessions command
+var sessionsCmd = &cobra.Command{
+	Use:   "sessions",
+	Short: "List and export stored conversations.",
+	Long: `List and export the conversations of 'schlama run' and the web chat.
+Conversations are stored in ` + session.Dir() + `.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+var sessionsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List stored conversations, the most recent first.",
+	Run: func(cmd *cobra.Command, args []string) {
+		sessions, err := session.List()
+		if err != nil {
+			fmt.Println(Red("[Error] ") + err.Error())
+			return
+		}
+		if len(sessions) == 0 {
+			fmt.Println(Yellow("[Hint]") + " No conversations stored yet.")
+			return
+		}
+		fmt.Println(createSessionsTable(sessions))
+	},
+}
+
+var sessionsExportCmd = &cobra.Command{
+	Use:   "export <id>",
+	Short: "Export a conversation as Markdown, JSON or HTML.",
+	Long: `Export a conversation as Markdown, JSON or HTML, including the model, options and timestamps.
+A unique prefix of the id is enough. Without --output the export is printed.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			cmd.Help()
+			return
+		}
+		sess, err := session.Load(args[0])
+		if err != nil {
+			fmt.Println(Red("[Error] ") + err.Error())
+			return
+		}
+
+		format := exportFormat
+		if !cmd.Flags().Changed("format") && exportOutput != "" {
+			format = session.FormatFromPath(exportOutput)
+		}
+		if exportOutput == "" {
+			if err := session.Render(os.Stdout, sess, format); err != nil {
+				fmt.Println(Red("[Error] ") + err.Error())
+			}
+			return
+		}
+
+		// render first, an unknown format must not leave an empty file behind
+		var buf bytes.Buffer
+		if err := session.Render(&buf, sess, format); err != nil {
+			fmt.Println(Red("[Error] ") + err.Error())
+			return
+		}
+		if err := os.WriteFile(exportOutput, buf.Bytes(), 0644); err != nil {
+			fmt.Println(Red("[Error] ") + err.Error())
+			return
+		}
+		fmt.Printf("%s Conversation exported to %s.\n", Green("[Msg]"), exportOutput)
+	},
+}
+
+func createSessionsTable(sessions []*session.Session) string {
+	var rows []string
+	header := fmt.Sprintf("%-24s %-18s %-24s %-8s %s", "ID", "UPDATED", "MODEL", "MESSAGES", "TITLE")
+	rows = append(rows, header)
+	divider := fmt.Sprintf("%-24s %-18s %-24s %-8s %s", strings.Repeat("-", 24), strings.Repeat("-", 18), strings.Repeat("-", 24), strings.Repeat("-", 8), strings.Repeat("-", 30))
+	rows = append(rows, divider)
+	for _, s := range sessions {
+		title := shorten(s.Label(), 40)
+		line := fmt.Sprintf("%-24s %-18s %-24s %-8d %s", s.ID, s.Updated.Format("2006-01-02 15:04"), s.Model, len(s.Branch()), title)
+		rows = append(rows, line)
+	}
+	return strings.Join(rows, "\n")
+}
+
+func init() {
+	sessionsExportCmd.Flags().StringVar(&exportFormat, "format", "md", "Export format: md, json or html")
+	sessionsExportCmd.Flags().StringVarP(&exportOutput, "output", "o", "", "Write the export to a file instead of printing it")
+	sessionsCmd.AddCommand(sessionsListCmd)
+	sessionsCmd.AddCommand(sessionsExportCmd)
+	rootCmd.AddCommand(sessionsCmd)
+}
@@ -0,0 +1,508 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/HanmaDevin/schlama/config"
+	"github.com/HanmaDevin/schlama/ollama"
+	"github.com/HanmaDevin/schlama/session"
+)
+
+// shell is the state of an interactive session of 'schlama run'.
+type shell struct {
+	// cfg is the request every prompt is sent with. Its messages only hold
+	// the system prompt, the conversation lives in sess.
+	cfg         *ollama.Ollama
+	sess        *session.Session
+	maxMessages int
+
+	// pending collects the attachments for the next prompt.
+	pending  ollama.Message
+	attached []string
+
+	// overrides made with /system and /set, they survive a model switch.
+	system    *string
+	options   map[string]any
+	keepAlive string
+}
+
+// slashCommand is a command of the interactive shell, e.g. /file.
+type slashCommand struct {
+	name  string
+	usage string
+	desc  string
+	// raw commands get the rest of the line as their only argument instead
+	// of the words of it.
+	raw bool
+	run func(sh *shell, args []string) error
+	// complete returns the candidates for the argument that starts with word.
+	complete func(word string) []string
+}
+
+// errExit ends the interactive shell.
+var errExit = errors.New("exit")
+
+var slashCommands []slashCommand
+
+func init() {
+	slashCommands = []slashCommand{
+		{name: "file", usage: "<path>...", desc: "Attach files to the next prompt", run: (*shell).attachFiles, complete: completePath},
+		{name: "dir", usage: "<path>...", desc: "Attach the files of directories to the next prompt", run: (*shell).attachDirs, complete: completePath},
+		{name: "image", usage: "<path>...", desc: "Attach images to the next prompt", run: (*shell).attachImages, complete: completePath},
+		{name: "edit", usage: "[N]", desc: "Write the next prompt in your editor, or rewrite prompt N and ask again", run: (*shell).edit},
+		{name: "model", usage: "<name>", desc: "Switch the model, the conversation is kept", run: (*shell).setModel, complete: completeModel},
+		{name: "system", usage: "[prompt]", desc: "Set the system prompt, without a prompt it is removed", raw: true, run: (*shell).setSystem},
+		{name: "set", usage: "<option> <value>", desc: "Set a model option or keep_alive, e.g. /set temperature 0.3", run: (*shell).setOption},
+		{name: "clear", desc: "Start a new conversation", run: (*shell).clear},
+		{name: "undo", desc: "Remove the last prompt and its answer", run: (*shell).undo},
+		{name: "retry", desc: "Ask for a new answer to the last prompt", run: (*shell).retry},
+		{name: "history", desc: "Show the conversation, prompts are numbered for /edit N", run: (*shell).history},
+		{name: "save", usage: "<file>", desc: "Export the conversation as .md, .json or .html", run: (*shell).save, complete: completePath},
+		{name: "load", usage: "<id>", desc: "Continue a stored conversation, see 'schlama sessions list'", run: (*shell).load, complete: completeSession},
+		{name: "help", desc: "Show this help", run: (*shell).help},
+		{name: "exit", desc: "Exit the interactive shell", run: func(*shell, []string) error { return errExit }},
+	}
+}
+
+func newShell(model string) (*shell, error) {
+	settings, err := config.Current()
+	if err != nil {
+		return nil, err
+	}
+	sh := &shell{maxMessages: settings.History.MaxMessages}
+	if err := sh.configure(model); err != nil {
+		return nil, err
+	}
+	sh.sess = session.New(sh.cfg, "run")
+	return sh, nil
+}
+
+// configure builds the request for model from the config and applies the
+// overrides of /system and /set.
+func (sh *shell) configure(model string) error {
+	cfg, err := config.ReadConfigFor(model)
+	if err != nil {
+		return err
+	}
+	if sh.system != nil {
+		cfg.Messages = nil
+		if *sh.system != "" {
+			cfg.Messages = []ollama.Message{{Role: "system", Content: *sh.system}}
+		}
+	}
+	if len(sh.options) > 0 {
+		if cfg.Options == nil {
+			cfg.Options = map[string]any{}
+		}
+		maps.Copy(cfg.Options, sh.options)
+	}
+	if sh.keepAlive != "" {
+		cfg.KeepAlive = sh.keepAlive
+	}
+	sh.cfg = cfg
+	if sh.sess != nil {
+		sh.sess.Configure(cfg)
+	}
+	return nil
+}
+
+// handle runs a line of input, either a slash command or a prompt.
+func (sh *shell) handle(line string) error {
+	if strings.HasPrefix(line, "//") {
+		// a doubled slash sends a prompt that starts with a slash
+		return sh.prompt(line[1:])
+	}
+	if !strings.HasPrefix(line, "/") {
+		return sh.prompt(line)
+	}
+
+	name, rest, _ := strings.Cut(line[1:], " ")
+	for _, c := range slashCommands {
+		if c.name != name {
+			continue
+		}
+		if c.raw {
+			return c.run(sh, []string{strings.TrimSpace(rest)})
+		}
+		args, err := splitArgs(rest)
+		if err != nil {
+			return err
+		}
+		return c.run(sh, args)
+	}
+	return fmt.Errorf("unknown command /%s, type /help to see available commands", name)
+}
+
+// prompt sends text together with the pending attachments.
+func (sh *shell) prompt(text string) error {
+	msg := sh.pending
+	msg.Role = "user"
+	msg.Content = text + msg.Content
+	sh.pending = ollama.Message{}
+	sh.attached = nil
+
+	head := sh.sess.Head
+	sh.sess.Add(msg)
+	return sh.sendOr(head)
+}
+
+// sendOr sends the active branch. If that fails, the message added for it is
+// removed and head becomes the active message again.
+func (sh *shell) sendOr(head int) error {
+	if err := sh.send(); err != nil {
+		if sh.sess.Head != head {
+			sh.sess.Drop(sh.sess.Head)
+		}
+		sh.sess.Checkout(head)
+		return err
+	}
+	return nil
+}
+
+// send asks the model to answer the conversation and stores the answer.
+func (sh *shell) send() error {
+	req := *sh.cfg
+	req.Messages = append(slices.Clone(sh.cfg.Messages), sh.sess.History()...)
+	req.Messages = ollama.TrimHistory(req.Messages, sh.maxMessages)
+	resp, err := ollama.GetResponse(&req)
+	if err != nil {
+		return fmt.Errorf("failed to get response from Ollama: %w", err)
+	}
+
+	sh.sess.Add(ollama.Message{
+		Role:    "assistant",
+		Content: resp,
+	})
+	sh.store()
+	println("\n" + resp)
+	return nil
+}
+
+// store writes the session, a conversation without messages is not worth
+// keeping.
+func (sh *shell) store() {
+	if len(sh.sess.Messages) == 0 {
+		return
+	}
+	if err := session.Save(sh.sess); err != nil {
+		println(Red(">>> [Error]")+" Not able to store the conversation:", err.Error())
+	}
+}
+
+func (sh *shell) attachFiles(args []string) error {
+	if len(args) == 0 {
+		return errors.New("please provide at least one file")
+	}
+	for _, path := range args {
+		path = expandPath(path)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("not able to read the specified file: %w", err)
+		}
+		sh.pending.Content += "\n\nFile: " + filepath.Base(path) + "\n" + string(data)
+		sh.attach(path)
+	}
+	return nil
+}
+
+func (sh *shell) attachDirs(args []string) error {
+	if len(args) == 0 {
+		return errors.New("please provide at least one directory")
+	}
+	for _, path := range args {
+		path = expandPath(path)
+		data, err := GetDirContent(path)
+		if err != nil {
+			return fmt.Errorf("not able to read the specified directory: %w", err)
+		}
+		sh.pending.Content += "\n\n" + data
+		sh.attach(path)
+	}
+	return nil
+}
+
+func (sh *shell) attachImages(args []string) error {
+	if len(args) == 0 {
+		return errors.New("please provide at least one image")
+	}
+	for _, path := range args {
+		path = expandPath(path)
+		encoded, err := EncodeImageToBase64(path)
+		if err != nil {
+			return fmt.Errorf("not able to read the specified image: %w", err)
+		}
+		sh.pending.Images = append(sh.pending.Images, encoded)
+		sh.attach(path)
+	}
+	return nil
+}
+
+func (sh *shell) attach(path string) {
+	sh.attached = append(sh.attached, path)
+	println(Yellow(">>> [Hint]")+" Attached", path+". Attachments are sent with your next prompt:", strings.Join(sh.attached, ", "))
+}
+
+func (sh *shell) edit(args []string) error {
+	if len(args) > 0 {
+		return sh.editPrompt(args[0])
+	}
+	text, err := editText("")
+	if err != nil {
+		return fmt.Errorf("not able to run the editor: %w", err)
+	}
+	if text == "" {
+		println(Yellow(">>> [Hint]") + " Empty prompt, nothing sent.")
+		return nil
+	}
+	println(text)
+	return sh.prompt(text)
+}
+
+// editPrompt rewrites the nth prompt of the active branch in the editor and
+// asks again from there. The old branch is kept in the session.
+func (sh *shell) editPrompt(arg string) error {
+	n, err := strconv.Atoi(arg)
+	prompts := sh.sess.Prompts()
+	if err != nil || n < 1 || n > len(prompts) {
+		return fmt.Errorf("there is no prompt %s, see /history", arg)
+	}
+	prompt := prompts[n-1]
+
+	text, err := editText(prompt.Content)
+	if err != nil {
+		return fmt.Errorf("not able to run the editor: %w", err)
+	}
+	if text == "" || text == strings.TrimSpace(prompt.Content) {
+		println(Yellow(">>> [Hint]") + " Prompt unchanged, nothing sent.")
+		return nil
+	}
+
+	head := sh.sess.Head
+	if err := sh.sess.Edit(prompt.ID, text); err != nil {
+		return err
+	}
+	println(text)
+	return sh.sendOr(head)
+}
+
+func (sh *shell) setModel(args []string) error {
+	if len(args) != 1 {
+		println(Yellow(">>> [Hint]")+" Current model:", sh.cfg.Model)
+		return nil
+	}
+	model := normalizeModel(args[0])
+	if !ollama.IsModelPresent(model) {
+		return fmt.Errorf("model %s not found, pull it with 'schlama pull %s'", model, model)
+	}
+	if err := sh.configure(model); err != nil {
+		return err
+	}
+	println(Green(">>> [Msg]")+" Switched to", model+".")
+	return nil
+}
+
+func (sh *shell) setSystem(args []string) error {
+	system := args[0]
+	sh.system = &system
+	if err := sh.configure(sh.cfg.Model); err != nil {
+		return err
+	}
+	if system == "" {
+		println(Green(">>> [Msg]") + " System prompt removed.")
+	} else {
+		println(Green(">>> [Msg]") + " System prompt set.")
+	}
+	return nil
+}
+
+func (sh *shell) setOption(args []string) error {
+	if len(args) < 2 {
+		return errors.New("usage: /set <option> <value>")
+	}
+	name, value := strings.TrimPrefix(args[0], "options."), strings.Join(args[1:], " ")
+
+	if name == "keep_alive" {
+		keepAlive, err := ollama.ParseKeepAlive(value)
+		if err != nil {
+			return err
+		}
+		sh.keepAlive = keepAlive
+	} else {
+		// the config validates the option just like 'schlama config set'
+		var scratch config.Config
+		if err := scratch.Set("options."+name, value); err != nil {
+			return err
+		}
+		if sh.options == nil {
+			sh.options = map[string]any{}
+		}
+		sh.options[name] = scratch.Options[name]
+	}
+	if err := sh.configure(sh.cfg.Model); err != nil {
+		return err
+	}
+	println(Green(">>> [Msg]")+" "+name, "set to", value+".")
+	return nil
+}
+
+func (sh *shell) clear([]string) error {
+	sh.sess = session.New(sh.cfg, "run")
+	sh.pending = ollama.Message{}
+	sh.attached = nil
+	println(Green(">>> [Msg]") + " Started a new conversation, session " + sh.sess.ID + ".")
+	return nil
+}
+
+func (sh *shell) undo([]string) error {
+	if !sh.sess.Undo() {
+		return errors.New("nothing to undo")
+	}
+	sh.store()
+	println(Green(">>> [Msg]") + " Removed the last prompt and its answer.")
+	return nil
+}
+
+func (sh *shell) retry([]string) error {
+	head := sh.sess.Head
+	if !sh.sess.Retry() {
+		return errors.New("nothing to retry")
+	}
+	return sh.sendOr(head)
+}
+
+// history prints the active branch, prompts are numbered for /edit N.
+func (sh *shell) history([]string) error {
+	n := 0
+	for _, m := range sh.sess.Branch() {
+		text := shorten(strings.ReplaceAll(m.Content, "\n", " "), 70)
+		if m.Role == "user" {
+			n++
+			println(Cyan(fmt.Sprintf(">>> %d.", n)), text)
+		} else {
+			println(Green(">>>   "), text)
+		}
+	}
+	if n == 0 {
+		println(Yellow(">>> [Hint]") + " Nothing asked yet.")
+	}
+	return nil
+}
+
+func (sh *shell) save(args []string) error {
+	if len(args) != 1 {
+		return errors.New("please provide a file to save the conversation to")
+	}
+	path := expandPath(args[0])
+	if err := exportSession(sh.sess, path); err != nil {
+		return fmt.Errorf("not able to save the conversation: %w", err)
+	}
+	println(Green(">>> [Msg]")+" Conversation saved to", path)
+	return nil
+}
+
+func (sh *shell) load(args []string) error {
+	if len(args) != 1 {
+		return errors.New("please provide the id of a conversation")
+	}
+	sess, err := session.Load(args[0])
+	if err != nil {
+		return err
+	}
+	if !ollama.IsModelPresent(sess.Model) {
+		return fmt.Errorf("model %s of the conversation not found, pull it with 'schlama pull %s'", sess.Model, sess.Model)
+	}
+
+	// continue with the settings the conversation was held with
+	system := sess.System
+	sh.system = &system
+	sh.options = maps.Clone(sess.Options)
+	sh.sess = sess
+	if err := sh.configure(sess.Model); err != nil {
+		return err
+	}
+	println(Green(">>> [Msg]")+" Loaded conversation", sess.ID, "with", len(sess.Branch()), "messages.")
+	return nil
+}
+
+func (sh *shell) help([]string) error {
+	println(Yellow(">>>") + " Ask something, or use the following commands:")
+	for _, c := range slashCommands {
+		usage := "/" + c.name
+		if c.usage != "" {
+			usage += " " + c.usage
+		}
+		println(Yellow(">>>") + fmt.Sprintf(" %-22s %s", usage, c.desc))
+	}
+	println(Yellow(">>>") + " Paths with spaces can be quoted. Start a prompt with '//' to send a leading '/'.")
+	return nil
+}
+
+// splitArgs splits the arguments of a slash command into words. Words can be
+// quoted with single or double quotes and a backslash escapes a space, quote
+// or backslash. Other backslashes are kept so Windows paths work unquoted.
+func splitArgs(line string) ([]string, error) {
+	var (
+		args   []string
+		word   strings.Builder
+		inWord bool
+		quote  rune
+	)
+	runes := []rune(line)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+				continue
+			}
+			if r == '\\' && quote == '"' && i+1 < len(runes) && strings.ContainsRune(`"\`, runes[i+1]) {
+				i++
+				r = runes[i]
+			}
+			word.WriteRune(r)
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case r == '\\' && i+1 < len(runes) && strings.ContainsRune(` '"\`, runes[i+1]):
+			i++
+			word.WriteRune(runes[i])
+			inWord = true
+		case unicode.IsSpace(r):
+			if inWord {
+				args = append(args, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("missing closing %c", quote)
+	}
+	if inWord {
+		args = append(args, word.String())
+	}
+	return args, nil
+}
+
+// expandPath expands environment variables and a leading ~ in path.
+func expandPath(path string) string {
+	path = os.ExpandEnv(path)
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, path[1:])
+		}
+	}
+	return path
+}
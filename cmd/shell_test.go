@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitArgs(t *testing.T) {
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{"", nil},
+		{"   ", nil},
+		{"a b  c", []string{"a", "b", "c"}},
+		{"\ta\t b ", []string{"a", "b"}},
+		{`"a b" c`, []string{"a b", "c"}},
+		{`'a b' c`, []string{"a b", "c"}},
+		{`pre"mid dle"post`, []string{"premid dlepost"}},
+		{`""`, []string{""}},
+		{`a '' b`, []string{"a", "", "b"}},
+		{`"it's"`, []string{"it's"}},
+		{`'say "hi"'`, []string{`say "hi"`}},
+		{`"say \"hi\""`, []string{`say "hi"`}},
+		{`'no \' escapes'x'`, []string{`no \`, "escapesx"}},
+		{`a\ b`, []string{"a b"}},
+		{`\"a\"`, []string{`"a"`}},
+		{`a\\b`, []string{`a\b`}},
+		{`C:\Users\me\file.txt`, []string{`C:\Users\me\file.txt`}},
+		{`"C:\Users\me"`, []string{`C:\Users\me`}},
+		{"/file ~/notes.md äöü", []string{"/file", "~/notes.md", "äöü"}},
+	}
+	for _, tt := range tests {
+		got, err := splitArgs(tt.line)
+		if err != nil {
+			t.Errorf("splitArgs(%q) error = %v", tt.line, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitArgs(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestSplitArgsErrors(t *testing.T) {
+	tests := map[string]string{
+		`"open`:     `missing closing "`,
+		`'open`:     `missing closing '`,
+		`a "b\" c`:  `missing closing "`,
+		`it's fine`: `missing closing '`,
+	}
+	for line, want := range tests {
+		if _, err := splitArgs(line); err == nil || err.Error() != want {
+			t.Errorf("splitArgs(%q) error = %v, want %q", line, err, want)
+		}
+	}
+}
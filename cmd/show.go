@@ -2,32 +2,29 @@ package cmd
 
 import (
 	"fmt"
-	"regexp"
+	"strings"
 
 	"github.com/HanmaDevin/schlama/ollama"
 	"github.com/spf13/cobra"
 )
 
+var showModelfile bool
+var showTemplate bool
+var showLicense bool
+var showParameters bool
+
 // showCmd represents the show command
 var showCmd = &cobra.Command{
-	Use:   "show",
+	Use:   "show <model>",
 	Short: "Show informataion about a model",
-	Long:  `Show informataion about a model`,
+	Long: `Show informataion about a model: architecture, parameters, context length, quantization,
+capabilities, system prompt, template and license.
+Use --modelfile, --template, --license or --parameters to print a single section in full.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		if len(args) != 1 {
 			cmd.Help()
 		} else {
-			// Check if the model is present in the local models
-			nameReg := regexp.MustCompile(`[\w\-]+\d?\.?\d?`)
-			name := nameReg.FindString(args[0])
-
-			labelReg := regexp.MustCompile(`:\w+\.?(\w+)?`)
-			label := labelReg.FindString(args[0])
-			if label == "" {
-				label = ":latest"
-			}
-
-			model := name + label
+			model := normalizeModel(args[0])
 
 			if !ollama.IsModelPresent(model) {
 				fmt.Println(Red("[Error]") + " Model not found. No information available.")
@@ -38,13 +35,88 @@ var showCmd = &cobra.Command{
 					fmt.Println(Red("[Error]") + " Unable to retrieve model information: " + err.Error())
 					return
 				}
-				fmt.Println(info)
+
+				if showModelfile || showTemplate || showLicense || showParameters {
+					if showModelfile {
+						fmt.Println(info.Modelfile)
+					}
+					if showParameters {
+						fmt.Println(info.Parameters)
+					}
+					if showTemplate {
+						fmt.Println(info.Template)
+					}
+					if showLicense {
+						fmt.Println(info.License)
+					}
+					return
+				}
+				fmt.Println(formatModelDetails(info))
 				return
 			}
 		}
 	},
 }
 
+func formatModelDetails(info *ollama.ModelDetails) string {
+	var sb strings.Builder
+
+	sb.WriteString(Cyan("Model") + "\n")
+	row := func(key string, value string) {
+		if value != "" && value != "0" {
+			fmt.Fprintf(&sb, "  %-20s %s\n", key, value)
+		}
+	}
+	row("architecture", info.Architecture())
+	row("parameters", info.Details.ParameterSize)
+	row("context length", fmt.Sprint(info.ContextLength()))
+	row("embedding length", fmt.Sprint(info.EmbeddingLength()))
+	row("quantization", info.Details.QuantizationLevel)
+
+	if len(info.Capabilities) > 0 {
+		sb.WriteString("\n" + Cyan("Capabilities") + "\n")
+		for _, c := range info.Capabilities {
+			sb.WriteString("  " + c + "\n")
+		}
+	}
+
+	section := func(title string, text string, maxLines int, flag string) {
+		text = strings.TrimSpace(text)
+		if text == "" {
+			return
+		}
+		sb.WriteString("\n" + Cyan(title) + "\n")
+		var lines []string
+		for line := range strings.SplitSeq(text, "\n") {
+			if strings.TrimSpace(line) != "" {
+				lines = append(lines, line)
+			}
+		}
+		// count the hidden lines before lines is cut down
+		hidden := 0
+		if maxLines > 0 && len(lines) > maxLines {
+			hidden = len(lines) - maxLines
+			lines = lines[:maxLines]
+		}
+		for _, line := range lines {
+			sb.WriteString("  " + line + "\n")
+		}
+		if hidden > 0 {
+			fmt.Fprintf(&sb, "  %s\n", Yellow(fmt.Sprintf("... %d more lines, use --%s to see all", hidden, flag)))
+		}
+	}
+	section("Parameters", info.Parameters, 0, "parameters")
+	section("System", info.System, 0, "")
+	section("Template", info.Template, 10, "template")
+	section("License", info.License, 5, "license")
+
+	return strings.TrimRight(sb.String(), "\n")
+}
+
 func init() {
+	showCmd.Flags().BoolVar(&showModelfile, "modelfile", false, "Print the Modelfile of the model.")
+	showCmd.Flags().BoolVar(&showTemplate, "template", false, "Print the prompt template of the model.")
+	showCmd.Flags().BoolVar(&showLicense, "license", false, "Print the license of the model.")
+	showCmd.Flags().BoolVar(&showParameters, "parameters", false, "Print the parameters of the model.")
 	rootCmd.AddCommand(showCmd)
 }
@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/HanmaDevin/schlama/ollama"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	prune   bool
+	syncYes bool
+)
+
+// ModelSet is the declarative list of models read by 'schlama sync'.
+type ModelSet struct {
+	Models []string `yaml:"models"`
+}
+
+type syncResult struct {
+	Model  string
+	Action string
+}
+
+// syncCmd represents the sync command
+var syncCmd = &cobra.Command{
+	Use:   "sync <models.yaml>",
+	Short: "Make the local models match a model file.",
+	Long: `Sync reads a YAML file with a list of models and pulls every model that is not present locally.
+With --prune, local models that are not listed in the file are removed after a confirmation,
+use --yes to skip it. A file that lists no models never removes anything.
+
+Example file:
+
+  models:
+    - llama3
+    - qwen2:7b`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			cmd.Help()
+			return
+		}
+
+		data, err := os.ReadFile(args[0])
+		if err != nil {
+			fmt.Println(Red("[Error]") + " Not able to read the specified file!")
+			return
+		}
+		var set ModelSet
+		if err := yaml.Unmarshal(data, &set); err != nil {
+			fmt.Println(Red("[Error]") + " Not able to parse the specified file: " + err.Error())
+			return
+		}
+
+		local, err := ollama.LocalModels()
+		if err != nil {
+			fmt.Println(Red("[Error] ") + err.Error())
+			return
+		}
+		if prune && len(set.Models) == 0 {
+			fmt.Println(Red("[Error]") + " The file lists no models, refusing to prune every local model. Check that the key is 'models'.")
+			return
+		}
+
+		var wanted []string
+		var missing []string
+		var results []syncResult
+		for _, m := range set.Models {
+			model := normalizeModel(m)
+			if slices.Contains(wanted, model) {
+				continue
+			}
+			wanted = append(wanted, model)
+			if slices.Contains(local, model) {
+				results = append(results, syncResult{Model: model, Action: "present"})
+				continue
+			}
+			missing = append(missing, model)
+		}
+
+		var stale []string
+		if prune {
+			for _, model := range local {
+				if !slices.Contains(wanted, model) {
+					stale = append(stale, model)
+				}
+			}
+		}
+		if len(stale) > 0 && !syncYes {
+			fmt.Printf("%s These local models are not listed and will be removed:\n", Yellow("[Hint]"))
+			for _, model := range stale {
+				fmt.Println("  " + model)
+			}
+			if !confirm("Remove them?") {
+				fmt.Println(Yellow("[Hint]") + " Not removing any models.")
+				stale = nil
+			}
+		}
+
+		if len(missing) > 0 {
+			fmt.Printf("%s Pulling %d missing model(s)...\n", Yellow("[Hint]"), len(missing))
+			for i, err := range ollama.PullModels(missing) {
+				action := "pulled"
+				if err != nil {
+					action = "failed: " + err.Error()
+				}
+				results = append(results, syncResult{Model: missing[i], Action: action})
+			}
+		}
+
+		for _, model := range stale {
+			action := "removed"
+			if err := ollama.RemoveModel(model); err != nil {
+				action = "failed: " + err.Error()
+			}
+			results = append(results, syncResult{Model: model, Action: action})
+		}
+
+		fmt.Println(createSyncTable(results))
+	},
+}
+
+// confirm asks a yes or no question on the terminal, no is the default.
+func confirm(question string) bool {
+	fmt.Printf("%s %s [y/N] ", Yellow("[Hint]"), question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	default:
+		return false
+	}
+}
+
+func createSyncTable(results []syncResult) string {
+	var rows []string
+	header := fmt.Sprintf("%-40s %-25s", "MODEL", "ACTION")
+	rows = append(rows, header)
+	divider := fmt.Sprintf("%-40s %-25s", strings.Repeat("-", 40), strings.Repeat("-", 25))
+	rows = append(rows, divider)
+	for _, r := range results {
+		rows = append(rows, fmt.Sprintf("%-40s %-25s", r.Model, r.Action))
+	}
+	return strings.Join(rows, "\n")
+}
+
+func init() {
+	syncCmd.Flags().BoolVar(&prune, "prune", false, "Remove local models that are not listed in the file.")
+	syncCmd.Flags().BoolVarP(&syncYes, "yes", "y", false, "Remove models with --prune without asking.")
+	rootCmd.AddCommand(syncCmd)
+}
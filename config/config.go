@@ -1,61 +1,342 @@
 package config
 
 import (
+	"fmt"
+	"maps"
 	"os"
-	"path/filepath"
+	"strings"
 
+	"github.com/HanmaDevin/schlama/imaging"
 	"github.com/HanmaDevin/schlama/ollama"
-	"gopkg.in/yaml.v3"
 )
 
-var home, _ = os.UserHomeDir()
-var config_Path string = filepath.Dir(home + "/.config/schlama/")
-var filename string = config_Path + "/config.yaml"
+// profile is the named profile that is applied on top of the config file.
+var profile string
 
 type Config struct {
-	Model string `yaml:"model"`
+	Version   int                    `yaml:"version,omitempty"`
+	Model     string                 `yaml:"model,omitempty"`
+	Host      string                 `yaml:"host,omitempty"`
+	System    string                 `yaml:"system,omitempty"`
+	KeepAlive string                 `yaml:"keep_alive,omitempty"`
+	Options   map[string]any         `yaml:"options,omitempty"`
+	Web       Web                    `yaml:"web,omitempty"`
+	History   History                `yaml:"history,omitempty"`
+	Models    map[string]ModelConfig `yaml:"models,omitempty"`
+	Profiles  map[string]Config      `yaml:"profiles,omitempty"`
+
+	// set holds the keys that were set explicitly, so an empty or zero value
+	// still overrides the layers below.
+	set map[string]bool
 }
 
-func ReadConfig() *ollama.Ollama {
-	var cfg Config
-	data, err := os.ReadFile(filename)
+// Web holds the settings of the web chat.
+type Web struct {
+	Port  int    `yaml:"port,omitempty"`
+	Theme string `yaml:"theme,omitempty"`
+	// Password protects the web chat when it is set.
+	Password string `yaml:"password,omitempty"`
+	// MaxImageSize is the longest side in pixels of the images the web chat
+	// sends to models, larger ones are downscaled.
+	MaxImageSize int `yaml:"max_image_size,omitempty"`
+}
+
+// History limits how much of a conversation is kept.
+type History struct {
+	// MaxMessages is the number of previous messages sent along with a
+	// prompt. 0 sends the whole conversation.
+	MaxMessages int `yaml:"max_messages,omitempty"`
+}
+
+// ModelConfig overrides the global settings for a single model.
+type ModelConfig struct {
+	System    string         `yaml:"system,omitempty"`
+	KeepAlive string         `yaml:"keep_alive,omitempty"`
+	Options   map[string]any `yaml:"options,omitempty"`
+}
+
+func defaults() Config {
+	return Config{
+		Host: ollama.DefaultHost,
+		Web: Web{
+			Port:         8080,
+			MaxImageSize: imaging.DefaultMaxSize,
+		},
+	}
+}
+
+// SetProfile selects the named profile that ReadConfig and Current apply on
+// top of the config file. An empty name falls back to $SCHLAMA_PROFILE.
+func SetProfile(name string) {
+	profile = name
+}
+
+// ActiveProfile returns the selected profile, or "" if none is selected.
+func ActiveProfile() string {
+	if profile != "" {
+		return profile
+	}
+	return os.Getenv("SCHLAMA_PROFILE")
+}
+
+// HasProfile reports whether the config file defines the named profile.
+func HasProfile(name string) (bool, error) {
+	cfg, err := LoadConfig()
+	if err != nil {
+		return false, err
+	}
+	_, ok := cfg.Profiles[name]
+	return ok, nil
+}
+
+// ReadConfig returns a request for the current model with all settings of
+// the config file, the selected profile and the model overrides applied.
+func ReadConfig() (*ollama.Ollama, error) {
+	cfg, err := Current()
 	if err != nil {
-		WriteConfig(Config{
-			Model: "",
-		})
+		return nil, err
 	}
-	// ignore errors, there shouldn't be any
-	err = yaml.Unmarshal(data, &cfg)
+	return parseConfig(cfg, cfg.Model), nil
+}
+
+// ReadConfigFor is like ReadConfig but builds the request for model instead
+// of the current model.
+func ReadConfigFor(model string) (*ollama.Ollama, error) {
+	cfg, err := Current()
 	if err != nil {
-		return nil
+		return nil, err
+	}
+	return parseConfig(cfg, model), nil
+}
+
+// Current returns the effective settings. Later layers win: the defaults,
+// the config file, the selected profile, SCHLAMA_* environment variables and
+// finally command line flags.
+func Current() (Config, error) {
+	ls, err := layers()
+	if err != nil {
+		return Config{}, err
+	}
+	var cfg Config
+	for _, l := range ls {
+		merge(&cfg, l.cfg)
+	}
+	return cfg, nil
+}
+
+// Setting is an effective config value and the layer it came from.
+type Setting struct {
+	Key    string
+	Value  string
+	Source string
+}
+
+// Explain returns every effective setting together with its source.
+func Explain() ([]Setting, error) {
+	ls, err := layers()
+	if err != nil {
+		return nil, err
 	}
+	var cfg Config
+	sources := map[string]string{}
+	for _, l := range ls {
+		merge(&cfg, l.cfg)
+		for _, key := range l.cfg.Keys() {
+			sources[key] = l.source
+		}
+	}
+
+	var settings []Setting
+	for _, key := range cfg.Keys() {
+		value, _, _ := cfg.Get(key)
+		settings = append(settings, Setting{Key: key, Value: value, Source: sources[key]})
+	}
+	return settings, nil
+}
 
-	return parseConfig(cfg)
+// layer is one source of settings.
+type layer struct {
+	source string
+	cfg    Config
 }
 
-func WriteConfig(cfg Config) error {
-	// Ensure the config directory exists
-	if err := os.MkdirAll(config_Path, 0755); err != nil {
-		return err
+func layers() ([]layer, error) {
+	file, err := LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+	ls := []layer{
+		{source: "default", cfg: defaults()},
+		{source: "file " + Path(), cfg: file},
+	}
+	if p := ActiveProfile(); p != "" {
+		if pc, ok := file.Profiles[p]; ok {
+			ls = append(ls, layer{source: "profile " + p, cfg: pc})
+		}
+	}
+
+	env, err := envLayers()
+	if err != nil {
+		return nil, err
 	}
+	ls = append(ls, env...)
+	ls = append(ls, flagLayers...)
 
-	data, err := yaml.Marshal(&cfg)
+	// per model variables can only be matched against known model names.
+	// Appending them last is fine, flags never set per model keys.
+	var cfg Config
+	for _, l := range ls {
+		merge(&cfg, l.cfg)
+	}
+	models := sortedKeys(cfg.Models)
+	if cfg.Model != "" {
+		models = append(models, cfg.Model)
+	}
+	envModels, err := envModelLayers(models)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	return append(ls, envModels...), nil
+}
+
+// Update changes the layer of the selected profile (or the top level if no
+// profile is selected) and writes the config file. The file is locked while
+// fn runs, so concurrent updates from the web chat and the CLI don't get lost.
+func Update(fn func(layer *Config) error) error {
+	profile := ActiveProfile()
+	return update(func(cfg *Config) error {
+		if profile == "" {
+			return fn(cfg)
+		}
+
+		layer := cfg.Profiles[profile]
+		if err := fn(&layer); err != nil {
+			return err
+		}
+		if cfg.Profiles == nil {
+			cfg.Profiles = map[string]Config{}
+		}
+		cfg.Profiles[profile] = layer
+		return nil
+	})
+}
+
+// Validate checks every key of cfg and its profiles.
+func Validate(cfg Config) error {
+	var errs []string
+	check := func(prefix string, c Config) {
+		var scratch Config
+		for _, key := range c.Keys() {
+			value, _, _ := c.Get(key)
+			if err := scratch.Set(key, value); err != nil {
+				errs = append(errs, prefix+err.Error())
+			}
+		}
+	}
+	check("", cfg)
+	for name, p := range cfg.Profiles {
+		if len(p.Profiles) > 0 {
+			errs = append(errs, fmt.Sprintf("profile %s: profiles can not be nested", name))
+		}
+		check(fmt.Sprintf("profile %s: ", name), p)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "\n"))
+	}
+	return nil
+}
+
+// merge copies every setting that is set in src over dst, empty and zero
+// values included if src sets them explicitly.
+func merge(dst *Config, src Config) {
+	copyKey := func(key string, copy func()) {
+		if _, ok, _ := src.Get(key); ok {
+			copy()
+			dst.mark(key)
+		}
+	}
+	copyKey("model", func() { dst.Model = src.Model })
+	copyKey("host", func() { dst.Host = src.Host })
+	copyKey("system", func() { dst.System = src.System })
+	copyKey("keep_alive", func() { dst.KeepAlive = src.KeepAlive })
+	copyKey("web.port", func() { dst.Web.Port = src.Web.Port })
+	copyKey("web.theme", func() { dst.Web.Theme = src.Web.Theme })
+	copyKey("web.password", func() { dst.Web.Password = src.Web.Password })
+	copyKey("web.max_image_size", func() { dst.Web.MaxImageSize = src.Web.MaxImageSize })
+	copyKey("history.max_messages", func() { dst.History.MaxMessages = src.History.MaxMessages })
+	if len(src.Options) > 0 {
+		if dst.Options == nil {
+			dst.Options = map[string]any{}
+		}
+		maps.Copy(dst.Options, src.Options)
+	}
+	for name, m := range src.Models {
+		if dst.Models == nil {
+			dst.Models = map[string]ModelConfig{}
+		}
+		d := dst.Models[name]
+		copyKey("models."+name+".system", func() { d.System = m.System })
+		copyKey("models."+name+".keep_alive", func() { d.KeepAlive = m.KeepAlive })
+		if len(m.Options) > 0 {
+			if d.Options == nil {
+				d.Options = map[string]any{}
+			}
+			maps.Copy(d.Options, m.Options)
+		}
+		dst.Models[name] = d
 	}
-	return os.WriteFile(filename, data, 0644)
 }
 
-func parseConfig(cfg Config) *ollama.Ollama {
+// modelConfig returns the overrides for model and the name they are stored
+// under. Overrides can be stored with or without the ':latest' tag.
+func (c *Config) modelConfig(model string) (string, ModelConfig, bool) {
+	if m, ok := c.Models[model]; ok {
+		return model, m, true
+	}
+	name := strings.TrimSuffix(model, ":latest")
+	m, ok := c.Models[name]
+	return name, m, ok
+}
+
+func parseConfig(cfg Config, model string) *ollama.Ollama {
+	system := cfg.System
+	keepAlive := cfg.KeepAlive
+	options := maps.Clone(cfg.Options)
+	// the model may not be known to Current, pick up its variables here
+	if envModels, err := envModelLayers([]string{model}); err == nil {
+		for _, l := range envModels {
+			merge(&cfg, l.cfg)
+		}
+	}
+	if name, m, ok := cfg.modelConfig(model); ok {
+		if _, ok, _ := cfg.Get("models." + name + ".system"); ok {
+			system = m.System
+		}
+		if _, ok, _ := cfg.Get("models." + name + ".keep_alive"); ok {
+			keepAlive = m.KeepAlive
+		}
+		if len(m.Options) > 0 {
+			if options == nil {
+				options = map[string]any{}
+			}
+			maps.Copy(options, m.Options)
+		}
+	}
+
 	Body := ollama.NewOllama()
-	Body.Model = cfg.Model
-	Body.Messages = []ollama.Message{
-		{
-			Role:    "user",
-			Content: "",
-			Images:  nil,
-		},
+	Body.Model = model
+	if system != "" {
+		Body.Messages = []ollama.Message{
+			{
+				Role:    "system",
+				Content: system,
+			},
+		}
 	}
 	Body.Stream = false
+	Body.Options = options
+	if keepAlive, err := ollama.ParseKeepAlive(keepAlive); err == nil {
+		Body.KeepAlive = keepAlive
+	}
 	return Body
 }
@@ -0,0 +1,239 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// useFile points the package at a config file with the given content and
+// resets the profile and flags when the test ends.
+func useFile(t *testing.T, content string) string {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SCHLAMA_PROFILE", "")
+	SetPath(p)
+	t.Cleanup(func() {
+		SetPath("")
+		SetProfile("")
+		flagLayers = nil
+	})
+	return p
+}
+
+const testFile = `version: 1
+model: llama3:latest
+system: from file
+web:
+  port: 9000
+history:
+  max_messages: 20
+options:
+  temperature: 0.5
+profiles:
+  work:
+    system: ""
+    web:
+      port: 9100
+    history:
+      max_messages: 0
+`
+
+func TestPrecedence(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile string
+		env     map[string]string
+		flags   map[string]string
+		key     string
+		value   string
+		source  string
+	}{
+		{name: "default", key: "host", value: "http://localhost:11434", source: "default"},
+		{name: "file over default", key: "web.port", value: "9000", source: "file"},
+		{name: "profile over file", profile: "work", key: "web.port", value: "9100", source: "profile work"},
+		{name: "empty profile value over file", profile: "work", key: "system", value: "", source: "profile work"},
+		{name: "zero profile value over file", profile: "work", key: "history.max_messages", value: "0", source: "profile work"},
+		{name: "file without profile", key: "system", value: "from file", source: "file"},
+		{
+			name:    "env over profile",
+			profile: "work",
+			env:     map[string]string{"SCHLAMA_WEB_PORT": "9200"},
+			key:     "web.port", value: "9200", source: "env SCHLAMA_WEB_PORT",
+		},
+		{
+			name:  "zero env value over file",
+			env:   map[string]string{"SCHLAMA_HISTORY_MAX_MESSAGES": "0"},
+			key:   "history.max_messages",
+			value: "0", source: "env SCHLAMA_HISTORY_MAX_MESSAGES",
+		},
+		{
+			name:  "env option",
+			env:   map[string]string{"SCHLAMA_OPTIONS_TEMPERATURE": "0.3"},
+			key:   "options.temperature",
+			value: "0.3", source: "env SCHLAMA_OPTIONS_TEMPERATURE",
+		},
+		{
+			name:  "env per model value of the current model",
+			env:   map[string]string{"SCHLAMA_MODELS_LLAMA3_LATEST_KEEP_ALIVE": "10m"},
+			key:   "models.llama3:latest.keep_alive",
+			value: "10m", source: "env SCHLAMA_MODELS_LLAMA3_LATEST_KEEP_ALIVE",
+		},
+		{
+			name:  "flag over env",
+			env:   map[string]string{"SCHLAMA_HOST": "http://env:11434"},
+			flags: map[string]string{"host": "http://flag:11434"},
+			key:   "host", value: "http://flag:11434", source: "flag --host",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useFile(t, testFile)
+			SetProfile(tt.profile)
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			for k, v := range tt.flags {
+				if err := SetFlag(k, v, "--"+k); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			cfg, err := Current()
+			if err != nil {
+				t.Fatal(err)
+			}
+			value, ok, err := cfg.Get(tt.key)
+			if err != nil || !ok || value != tt.value {
+				t.Errorf("Get(%q) = %q, %v, %v, want %q", tt.key, value, ok, err, tt.value)
+			}
+
+			settings, err := Explain()
+			if err != nil {
+				t.Fatal(err)
+			}
+			source := ""
+			for _, s := range settings {
+				if s.Key == tt.key {
+					source = s.Source
+				}
+			}
+			if !strings.HasPrefix(source, tt.source) {
+				t.Errorf("Explain() source of %s = %q, want %q", tt.key, source, tt.source)
+			}
+		})
+	}
+}
+
+func TestEnvErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		env  string
+		val  string
+	}{
+		{"port not a number", "SCHLAMA_WEB_PORT", "abc"},
+		{"port out of range", "SCHLAMA_WEB_PORT", "70000"},
+		{"unknown theme", "SCHLAMA_WEB_THEME", "nope"},
+		{"bad keep_alive", "SCHLAMA_KEEP_ALIVE", "soon"},
+		{"bad option", "SCHLAMA_OPTIONS_NUM_CTX", "lots"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useFile(t, testFile)
+			t.Setenv(tt.env, tt.val)
+			_, err := Current()
+			if err == nil || !strings.HasPrefix(err.Error(), tt.env+": ") {
+				t.Errorf("Current() error = %v, want an error about %s", err, tt.env)
+			}
+		})
+	}
+}
+
+func TestEnvName(t *testing.T) {
+	tests := map[string]string{
+		"web.port":                        "SCHLAMA_WEB_PORT",
+		"options.num_ctx":                 "SCHLAMA_OPTIONS_NUM_CTX",
+		"models.llama3:latest.system":     "SCHLAMA_MODELS_LLAMA3_LATEST_SYSTEM",
+		"models.qwen2.5-coder:7b.options": "SCHLAMA_MODELS_QWEN2_5_CODER_7B_OPTIONS",
+	}
+	for key, want := range tests {
+		if got := EnvName(key); got != want {
+			t.Errorf("EnvName(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestExplicitValuesSurviveWrites(t *testing.T) {
+	p := useFile(t, testFile)
+	err := Update(func(layer *Config) error {
+		return layer.Set("keep_alive", "5m")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := decode(data, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	work := cfg.Profiles["work"]
+	for _, key := range []string{"system", "history.max_messages"} {
+		if _, ok, _ := work.Get(key); !ok {
+			t.Errorf("profile key %s was dropped when the file was written:\n%s", key, data)
+		}
+	}
+}
+
+func TestReplaceFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"valid", "model: qwen2:latest\n", ""},
+		{"unknown key", "bogus: 1\n", "the config is not valid YAML"},
+		{"unknown nested key", "web:\n  bogus: 1\n", "the config is not valid YAML"},
+		{"unknown profile key", "profiles:\n  p:\n    bogus: 1\n", "the config is not valid YAML"},
+		{"invalid value", "web:\n  port: 99999\n", "the config has invalid settings"},
+		{"nested profile", "profiles:\n  p:\n    profiles:\n      q: {}\n", "the config has invalid settings"},
+		{"newer version", "version: 99\n", "the config has version 99"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := useFile(t, testFile)
+			err := ReplaceFile([]byte(tt.content))
+			data, _ := os.ReadFile(p)
+			if tt.wantErr == "" {
+				if err != nil || string(data) != tt.content {
+					t.Errorf("ReplaceFile() error = %v, file = %q", err, data)
+				}
+				return
+			}
+			if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+				t.Errorf("ReplaceFile() error = %v, want %q", err, tt.wantErr)
+			}
+			if string(data) != testFile {
+				t.Errorf("ReplaceFile() changed the file on error:\n%s", data)
+			}
+		})
+	}
+}
+
+func TestLoadConfigDoesNotCreateFile(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "config.yaml")
+	SetPath(p)
+	t.Cleanup(func() { SetPath("") })
+	if _, err := LoadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(p); !os.IsNotExist(err) {
+		t.Errorf("LoadConfig() created %s", p)
+	}
+}
@@ -0,0 +1,109 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+const envPrefix = "SCHLAMA_"
+
+// flagLayers holds the settings given as command line flags.
+var flagLayers []layer
+
+// SetFlag applies a command line flag on top of every other layer. flag is
+// the name shown by 'schlama config explain', e.g. "--keep-alive".
+func SetFlag(key string, value string, flag string) error {
+	var cfg Config
+	if err := cfg.Set(key, value); err != nil {
+		return fmt.Errorf("%s: %w", flag, err)
+	}
+	flagLayers = append(flagLayers, layer{source: "flag " + flag, cfg: cfg})
+	return nil
+}
+
+// EnvName returns the environment variable that overrides key, e.g.
+// SCHLAMA_WEB_PORT for web.port.
+func EnvName(key string) string {
+	return envPrefix + envSafe(key)
+}
+
+// envKeys are the keys that can be overridden by a variable of their own.
+// Per model keys are handled by envModelLayers.
+func envKeys() []string {
+	var keys []string
+	for _, s := range settings {
+		keys = append(keys, s.name)
+	}
+	for _, name := range optionNames() {
+		keys = append(keys, "options."+name)
+	}
+	return keys
+}
+
+// envLayers returns one layer for every SCHLAMA_* variable that is set.
+func envLayers() ([]layer, error) {
+	var ls []layer
+	for _, key := range envKeys() {
+		name := EnvName(key)
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		var cfg Config
+		if err := cfg.Set(key, value); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		ls = append(ls, layer{source: "env " + name, cfg: cfg})
+	}
+	return ls, nil
+}
+
+// envModelLayers returns the layers of the per model variables of the given
+// models, e.g. SCHLAMA_MODELS_LLAMA3_LATEST_OPTIONS_NUM_CTX for
+// models.llama3:latest.options.num_ctx. Model names are not recoverable from
+// a variable name, so only variables of known models are picked up.
+func envModelLayers(models []string) ([]layer, error) {
+	var ls []layer
+	seen := map[string]bool{}
+	for _, model := range models {
+		if seen[model] {
+			continue
+		}
+		seen[model] = true
+
+		fields := []string{"system", "keep_alive"}
+		for _, name := range optionNames() {
+			fields = append(fields, "options."+name)
+		}
+		for _, field := range fields {
+			key := "models." + model + "." + field
+			name := EnvName(key)
+			value, ok := os.LookupEnv(name)
+			if !ok {
+				continue
+			}
+			var cfg Config
+			if err := cfg.Set(key, value); err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			ls = append(ls, layer{source: "env " + name, cfg: cfg})
+		}
+	}
+	return ls, nil
+}
+
+// envSafe upper cases s and replaces everything but letters and digits with
+// underscores.
+func envSafe(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, s)
+}
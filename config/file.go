@@ -0,0 +1,346 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// currentVersion is the version of the config file schema. Version 0 is the
+// original file that only held the model.
+const currentVersion = 1
+
+// path is the config file given with --config.
+var path string
+
+// SetPath makes schlama use the config file at p instead of the default
+// location.
+func SetPath(p string) {
+	path = p
+}
+
+// Path returns the location of the config file: the path set with SetPath,
+// then $SCHLAMA_CONFIG, then config.yaml in Dir.
+func Path() string {
+	if path != "" {
+		return path
+	}
+	if p := os.Getenv("SCHLAMA_CONFIG"); p != "" {
+		return p
+	}
+	return filepath.Join(Dir(), "config.yaml")
+}
+
+// Dir returns the schlama config directory, $XDG_CONFIG_HOME/schlama or
+// ~/.config/schlama.
+func Dir() string {
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" && filepath.IsAbs(xdg) {
+		return filepath.Join(xdg, "schlama")
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".config", "schlama")
+}
+
+// legacyPath is where schlama stored its config before it honored
+// $XDG_CONFIG_HOME.
+func legacyPath() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".config", "schlama", "config.yaml")
+}
+
+// LoadConfig returns the settings stored in the config file. A missing file
+// is not an error, all settings are unset then and no file is created. Files
+// of an older schema are migrated and written back.
+func LoadConfig() (Config, error) {
+	cfg, exists, err := readFile()
+	if err != nil {
+		return Config{}, err
+	}
+	if exists && cfg.Version < currentVersion {
+		migrate(&cfg)
+		// writing back is best effort, an old or read-only file is still usable
+		update(func(*Config) error { return nil })
+	}
+	return cfg, nil
+}
+
+// WriteConfig replaces the config file with cfg.
+func WriteConfig(cfg Config) error {
+	unlock, err := lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return writeFile(cfg)
+}
+
+// ReplaceFile replaces the config file with data, as it is, so comments and
+// layout of an edited file are kept. Nothing is written if data is not a
+// valid config.
+func ReplaceFile(data []byte) error {
+	cfg, err := decode(data, true)
+	if err != nil {
+		return fmt.Errorf("the config is not valid YAML: %w", err)
+	}
+	if cfg.Version > currentVersion {
+		return fmt.Errorf("the config has version %d, this schlama only knows version %d", cfg.Version, currentVersion)
+	}
+	if err := Validate(cfg); err != nil {
+		return fmt.Errorf("the config has invalid settings:\n%w", err)
+	}
+
+	unlock, err := lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return writeData(data)
+}
+
+// update runs fn on the whole config file while holding the lock and writes
+// the result back.
+func update(fn func(cfg *Config) error) error {
+	unlock, err := lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	cfg, _, err := readFile()
+	if err != nil {
+		return err
+	}
+	migrate(&cfg)
+	if err := fn(&cfg); err != nil {
+		return err
+	}
+	return writeFile(cfg)
+}
+
+// readFile reads the config file and reports whether it exists.
+func readFile() (Config, bool, error) {
+	var cfg Config
+	data, err := os.ReadFile(Path())
+	if os.IsNotExist(err) && path == "" && os.Getenv("SCHLAMA_CONFIG") == "" && Path() != legacyPath() {
+		data, err = os.ReadFile(legacyPath())
+	}
+	if os.IsNotExist(err) {
+		return cfg, false, nil
+	}
+	if err != nil {
+		return cfg, false, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	cfg, err = decode(data, false)
+	if err != nil {
+		return cfg, true, fmt.Errorf("failed to parse config file %s: %w", Path(), err)
+	}
+	if cfg.Version > currentVersion {
+		return cfg, true, fmt.Errorf("config file %s has version %d, this schlama only knows version %d", Path(), cfg.Version, currentVersion)
+	}
+	return cfg, true, nil
+}
+
+// writeFile encodes cfg and writes it to the config file.
+func writeFile(cfg Config) error {
+	data, err := yaml.Marshal(&cfg)
+	if err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+	return writeData(data)
+}
+
+// writeData writes data to a temporary file next to the config file and
+// renames it, so readers never see a half written file.
+func writeData(data []byte) error {
+	p := Path()
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".config-*.yaml")
+	if err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), p); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	return nil
+}
+
+// lock takes an exclusive lock on the config file. The returned function
+// releases it.
+func lock() (func(), error) {
+	p := Path() + ".lock"
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create config directory: %w", err)
+	}
+	f, err := os.OpenFile(p, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open config lock: %w", err)
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to lock config file: %w", err)
+	}
+	return func() {
+		unlockFile(f)
+		f.Close()
+	}, nil
+}
+
+// migrate brings a config file of an older schema up to date.
+func migrate(cfg *Config) {
+	if cfg.Version < 1 {
+		// version 0 files were written with an empty model key and no
+		// version, the fields themselves are unchanged
+		cfg.Version = 1
+	}
+}
+
+// plainConfig has the fields of Config without its MarshalYAML method.
+type plainConfig Config
+
+// decode parses a config file. Strict decoding rejects unknown keys. The keys
+// the file sets are recorded, so an empty or zero value in a profile still
+// overrides the top level.
+func decode(data []byte, strict bool) (Config, error) {
+	var cfg Config
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(strict)
+	if err := dec.Decode(&cfg); err != nil && err != io.EOF {
+		return cfg, err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil || len(doc.Content) == 0 {
+		return cfg, nil
+	}
+	root := doc.Content[0]
+	markYAML(&cfg, root)
+	for name, p := range cfg.Profiles {
+		markYAML(&p, yamlChild(root, "profiles", name))
+		cfg.Profiles[name] = p
+	}
+	return cfg, nil
+}
+
+// markYAML records the keys that the mapping node sets in c.
+func markYAML(c *Config, node *yaml.Node) {
+	if node == nil {
+		return
+	}
+	for _, s := range settings {
+		if yamlChild(node, strings.Split(s.name, ".")...) != nil {
+			c.mark(s.name)
+		}
+	}
+	if models := yamlChild(node, "models"); models != nil && models.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(models.Content); i += 2 {
+			for _, field := range []string{"system", "keep_alive"} {
+				if yamlChild(models.Content[i+1], field) != nil {
+					c.mark("models." + models.Content[i].Value + "." + field)
+				}
+			}
+		}
+	}
+}
+
+// MarshalYAML encodes a config. Keys that are set to an empty or zero value
+// are written too, the omitempty tags would drop them.
+func (c Config) MarshalYAML() (any, error) {
+	var node yaml.Node
+	if err := node.Encode(plainConfig(c)); err != nil {
+		return nil, err
+	}
+	for _, key := range sortedKeys(c.set) {
+		value := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: ""}
+		var path []string
+		if s, ok := lookupSetting(key); ok {
+			// Get shows an explicit zero as "0", the raw value is empty
+			if s.get(&c) != "" {
+				continue
+			}
+			path = strings.Split(key, ".")
+			if s.numeric {
+				value = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: "0"}
+			}
+		} else if rest, ok := strings.CutPrefix(key, "models."); ok {
+			model, field, err := splitModelKey(rest)
+			if v, _, _ := c.Get(key); err != nil || v != "" {
+				continue
+			}
+			path = []string{"models", model, field}
+		} else {
+			continue
+		}
+		setYAMLChild(&node, path, value)
+	}
+	return &node, nil
+}
+
+// yamlChild returns the value under path in the mapping node, or nil.
+func yamlChild(node *yaml.Node, path ...string) *yaml.Node {
+	for _, name := range path {
+		if node.Kind != yaml.MappingNode {
+			return nil
+		}
+		var next *yaml.Node
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == name {
+				next = node.Content[i+1]
+				break
+			}
+		}
+		if next == nil {
+			return nil
+		}
+		node = next
+	}
+	return node
+}
+
+// setYAMLChild stores value under path in the mapping node, missing mappings
+// on the way are created.
+func setYAMLChild(node *yaml.Node, path []string, value *yaml.Node) {
+	for i, name := range path {
+		next := yamlChild(node, name)
+		if next == nil {
+			next = value
+			if i < len(path)-1 {
+				next = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			}
+			// empty mappings are encoded as {}
+			node.Style &^= yaml.FlowStyle
+			key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: name}
+			// keep the profiles at the end of the file
+			at := len(node.Content)
+			if len(node.Content) >= 2 && node.Content[at-2].Value == "profiles" {
+				at -= 2
+			}
+			node.Content = slices.Insert(node.Content, at, key, next)
+		}
+		node = next
+	}
+}
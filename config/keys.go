@@ -0,0 +1,495 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/HanmaDevin/schlama/ollama"
+)
+
+type optionKind int
+
+const (
+	kindInt optionKind = iota
+	kindFloat
+	kindBool
+	kindList
+)
+
+// options are the model options ollama accepts in a request.
+var options = map[string]optionKind{
+	"num_ctx":           kindInt,
+	"num_predict":       kindInt,
+	"num_keep":          kindInt,
+	"num_batch":         kindInt,
+	"num_gpu":           kindInt,
+	"main_gpu":          kindInt,
+	"num_thread":        kindInt,
+	"seed":              kindInt,
+	"top_k":             kindInt,
+	"repeat_last_n":     kindInt,
+	"temperature":       kindFloat,
+	"top_p":             kindFloat,
+	"min_p":             kindFloat,
+	"typical_p":         kindFloat,
+	"repeat_penalty":    kindFloat,
+	"presence_penalty":  kindFloat,
+	"frequency_penalty": kindFloat,
+	"use_mmap":          kindBool,
+	"stop":              kindList,
+}
+
+// themes are the daisyUI themes the web chat can use.
+var themes = []string{
+	"light", "dark", "cupcake", "bumblebee", "emerald", "corporate", "synthwave", "retro",
+	"cyberpunk", "valentine", "halloween", "garden", "forest", "aqua", "lofi", "pastel",
+	"fantasy", "wireframe", "black", "luxury", "dracula", "cmyk", "autumn", "business",
+	"acid", "lemonade", "night", "coffee", "winter", "dim", "nord", "sunset",
+}
+
+// setting is a fixed config key.
+type setting struct {
+	name string
+	desc string
+	get  func(c *Config) string
+	set  func(c *Config, value string) error
+	// numeric settings are stored as numbers in the config file.
+	numeric bool
+	// secret settings are masked by Display.
+	secret bool
+}
+
+var settings = []setting{
+	{
+		name: "model",
+		desc: "Model used by prompt and the web chat",
+		get:  func(c *Config) string { return c.Model },
+		set:  func(c *Config, v string) error { c.Model = v; return nil },
+	},
+	{
+		name: "host",
+		desc: "Address of the ollama server",
+		get:  func(c *Config) string { return c.Host },
+		set: func(c *Config, v string) error {
+			if v != "" {
+				u, err := url.Parse(v)
+				if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+					return fmt.Errorf("host must be an http(s) url like %s", ollama.DefaultHost)
+				}
+				v = strings.TrimSuffix(v, "/")
+			}
+			c.Host = v
+			return nil
+		},
+	},
+	{
+		name: "system",
+		desc: "System prompt sent with every conversation",
+		get:  func(c *Config) string { return c.System },
+		set:  func(c *Config, v string) error { c.System = v; return nil },
+	},
+	{
+		name: "keep_alive",
+		desc: "How long a model stays loaded, e.g. 5m, 1h or -1",
+		get:  func(c *Config) string { return c.KeepAlive },
+		set: func(c *Config, v string) error {
+			if _, err := ollama.ParseKeepAlive(v); err != nil {
+				return err
+			}
+			c.KeepAlive = v
+			return nil
+		},
+	},
+	{
+		name: "web.port",
+		desc: "Port of the web chat",
+		get:  func(c *Config) string { return formatInt(c.Web.Port) },
+		set: func(c *Config, v string) error {
+			port, err := parseInt(v, 1, 65535)
+			if err != nil {
+				return fmt.Errorf("web.port: %w", err)
+			}
+			c.Web.Port = port
+			return nil
+		},
+		numeric: true,
+	},
+	{
+		name: "web.theme",
+		desc: "daisyUI theme of the web chat",
+		get:  func(c *Config) string { return c.Web.Theme },
+		set: func(c *Config, v string) error {
+			if v != "" && !slices.Contains(themes, v) {
+				return fmt.Errorf("unknown theme %q, valid themes: %s", v, strings.Join(themes, ", "))
+			}
+			c.Web.Theme = v
+			return nil
+		},
+	},
+	{
+		name: "web.password",
+		desc: "Password of the web chat, empty for none",
+		get:  func(c *Config) string { return c.Web.Password },
+		set: func(c *Config, v string) error {
+			c.Web.Password = v
+			return nil
+		},
+		secret: true,
+	},
+	{
+		name: "web.max_image_size",
+		desc: "Longest side in pixels of images sent by the web chat",
+		get:  func(c *Config) string { return formatInt(c.Web.MaxImageSize) },
+		set: func(c *Config, v string) error {
+			n, err := parseInt(v, 64, -1)
+			if err != nil {
+				return fmt.Errorf("web.max_image_size: %w", err)
+			}
+			c.Web.MaxImageSize = n
+			return nil
+		},
+		numeric: true,
+	},
+	{
+		name: "history.max_messages",
+		desc: "Previous messages sent with a prompt, 0 for all",
+		get:  func(c *Config) string { return formatInt(c.History.MaxMessages) },
+		set: func(c *Config, v string) error {
+			n, err := parseInt(v, 0, -1)
+			if err != nil {
+				return fmt.Errorf("history.max_messages: %w", err)
+			}
+			c.History.MaxMessages = n
+			return nil
+		},
+		numeric: true,
+	},
+}
+
+// KeyHelp describes the keys that can be used with Get, Set and Unset.
+func KeyHelp() string {
+	var rows []string
+	for _, s := range settings {
+		rows = append(rows, fmt.Sprintf("  %-32s %s", s.name, s.desc))
+	}
+	rows = append(rows, fmt.Sprintf("  %-32s %s", "options.<name>", "Model option, one of "+strings.Join(optionNames(), ", ")))
+	rows = append(rows, fmt.Sprintf("  %-32s %s", "models.<model>.system", "System prompt for one model"))
+	rows = append(rows, fmt.Sprintf("  %-32s %s", "models.<model>.keep_alive", "keep_alive for one model"))
+	rows = append(rows, fmt.Sprintf("  %-32s %s", "models.<model>.options.<name>", "Model option for one model"))
+	return strings.Join(rows, "\n")
+}
+
+// Keys returns every key that is set in c, in a stable order.
+func (c *Config) Keys() []string {
+	var keys []string
+	for _, s := range settings {
+		if c.isSet(s.name, s.get(c)) {
+			keys = append(keys, s.name)
+		}
+	}
+	for _, name := range sortedKeys(c.Options) {
+		keys = append(keys, "options."+name)
+	}
+	for _, model := range sortedKeys(c.Models) {
+		m := c.Models[model]
+		if c.isSet("models."+model+".system", m.System) {
+			keys = append(keys, "models."+model+".system")
+		}
+		if c.isSet("models."+model+".keep_alive", m.KeepAlive) {
+			keys = append(keys, "models."+model+".keep_alive")
+		}
+		for _, name := range sortedKeys(m.Options) {
+			keys = append(keys, "models."+model+".options."+name)
+		}
+	}
+	return keys
+}
+
+// Get returns the value of key and whether it is set.
+func (c *Config) Get(key string) (string, bool, error) {
+	if s, ok := lookupSetting(key); ok {
+		v := s.get(c)
+		ok := c.isSet(key, v)
+		if ok && v == "" && s.numeric {
+			// a zero that was set explicitly
+			v = "0"
+		}
+		return v, ok, nil
+	}
+	if name, ok := strings.CutPrefix(key, "options."); ok {
+		if _, err := optionKindOf(name); err != nil {
+			return "", false, err
+		}
+		v, ok := c.Options[name]
+		return formatOption(v), ok, nil
+	}
+	if rest, ok := strings.CutPrefix(key, "models."); ok {
+		model, field, err := splitModelKey(rest)
+		if err != nil {
+			return "", false, err
+		}
+		m := c.Models[model]
+		switch {
+		case field == "system":
+			return m.System, c.isSet(key, m.System), nil
+		case field == "keep_alive":
+			return m.KeepAlive, c.isSet(key, m.KeepAlive), nil
+		default:
+			name := strings.TrimPrefix(field, "options.")
+			v, ok := m.Options[name]
+			return formatOption(v), ok, nil
+		}
+	}
+	return "", false, unknownKey(key)
+}
+
+// Set validates value and stores it under key. An empty value is stored
+// too and overrides lower layers.
+func (c *Config) Set(key string, value string) error {
+	if s, ok := lookupSetting(key); ok {
+		if err := s.set(c, value); err != nil {
+			return err
+		}
+		c.mark(key)
+		return nil
+	}
+	if name, ok := strings.CutPrefix(key, "options."); ok {
+		v, err := parseOption(name, value)
+		if err != nil {
+			return err
+		}
+		if c.Options == nil {
+			c.Options = map[string]any{}
+		}
+		c.Options[name] = v
+		return nil
+	}
+	if rest, ok := strings.CutPrefix(key, "models."); ok {
+		model, field, err := splitModelKey(rest)
+		if err != nil {
+			return err
+		}
+		if c.Models == nil {
+			c.Models = map[string]ModelConfig{}
+		}
+		m := c.Models[model]
+		switch {
+		case field == "system":
+			m.System = value
+		case field == "keep_alive":
+			if _, err := ollama.ParseKeepAlive(value); err != nil {
+				return err
+			}
+			m.KeepAlive = value
+		default:
+			name := strings.TrimPrefix(field, "options.")
+			v, err := parseOption(name, value)
+			if err != nil {
+				return err
+			}
+			if m.Options == nil {
+				m.Options = map[string]any{}
+			}
+			m.Options[name] = v
+		}
+		c.Models[model] = m
+		if field == "system" || field == "keep_alive" {
+			c.mark(key)
+		}
+		return nil
+	}
+	return unknownKey(key)
+}
+
+// Unset removes key so the default or a lower layer applies again.
+func (c *Config) Unset(key string) error {
+	delete(c.set, key)
+	if s, ok := lookupSetting(key); ok {
+		return s.set(c, "")
+	}
+	if name, ok := strings.CutPrefix(key, "options."); ok {
+		if _, err := optionKindOf(name); err != nil {
+			return err
+		}
+		delete(c.Options, name)
+		return nil
+	}
+	if rest, ok := strings.CutPrefix(key, "models."); ok {
+		model, field, err := splitModelKey(rest)
+		if err != nil {
+			return err
+		}
+		m, ok := c.Models[model]
+		if !ok {
+			return nil
+		}
+		switch {
+		case field == "system":
+			m.System = ""
+		case field == "keep_alive":
+			m.KeepAlive = ""
+		default:
+			delete(m.Options, strings.TrimPrefix(field, "options."))
+		}
+		if !c.isSet("models."+model+".system", m.System) && !c.isSet("models."+model+".keep_alive", m.KeepAlive) && len(m.Options) == 0 {
+			delete(c.Models, model)
+		} else {
+			c.Models[model] = m
+		}
+		return nil
+	}
+	return unknownKey(key)
+}
+
+// mark records that key was set explicitly.
+func (c *Config) mark(key string) {
+	if c.set == nil {
+		c.set = map[string]bool{}
+	}
+	c.set[key] = true
+}
+
+// isSet reports whether key, whose current value is value, is set.
+func (c *Config) isSet(key string, value string) bool {
+	return value != "" || c.set[key]
+}
+
+// Display returns value as the config commands show it, secrets like the
+// password of the web chat are masked.
+func Display(key, value string) string {
+	if s, ok := lookupSetting(key); ok && s.secret && value != "" {
+		return "********"
+	}
+	return value
+}
+
+func lookupSetting(key string) (setting, bool) {
+	for _, s := range settings {
+		if s.name == key {
+			return s, true
+		}
+	}
+	return setting{}, false
+}
+
+// splitModelKey splits "<model>.<field>" where the model name itself may
+// contain dots, e.g. "qwen2.5:7b.options.temperature".
+func splitModelKey(rest string) (string, string, error) {
+	if i := strings.LastIndex(rest, ".options."); i > 0 {
+		name := rest[i+len(".options."):]
+		if _, err := optionKindOf(name); err != nil {
+			return "", "", err
+		}
+		return rest[:i], "options." + name, nil
+	}
+	i := strings.LastIndex(rest, ".")
+	if i <= 0 {
+		return "", "", unknownKey("models." + rest)
+	}
+	field := rest[i+1:]
+	if field != "system" && field != "keep_alive" {
+		return "", "", unknownKey("models." + rest)
+	}
+	return rest[:i], field, nil
+}
+
+func optionKindOf(name string) (optionKind, error) {
+	kind, ok := options[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown option %q, valid options: %s", name, strings.Join(optionNames(), ", "))
+	}
+	return kind, nil
+}
+
+func parseOption(name string, value string) (any, error) {
+	kind, err := optionKindOf(name)
+	if err != nil {
+		return nil, err
+	}
+	switch kind {
+	case kindInt:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("option %s must be a whole number", name)
+		}
+		return n, nil
+	case kindFloat:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("option %s must be a number", name)
+		}
+		return f, nil
+	case kindBool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("option %s must be true or false", name)
+		}
+		return b, nil
+	default:
+		var list []string
+		for _, s := range strings.Split(value, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				list = append(list, s)
+			}
+		}
+		return list, nil
+	}
+}
+
+func formatOption(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case []string:
+		return strings.Join(v, ",")
+	case []any:
+		parts := make([]string, len(v))
+		for i, p := range v {
+			parts[i] = fmt.Sprint(p)
+		}
+		return strings.Join(parts, ",")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func optionNames() []string {
+	return sortedKeys(options)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func parseInt(v string, min int, max int) (int, error) {
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < min || (max >= 0 && n > max) {
+		if max >= 0 {
+			return 0, fmt.Errorf("must be a whole number between %d and %d", min, max)
+		}
+		return 0, fmt.Errorf("must be a whole number of at least %d", min)
+	}
+	return n, nil
+}
+
+func formatInt(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
+func unknownKey(key string) error {
+	return fmt.Errorf("unknown key %q, see 'schlama config --help' for valid keys", key)
+}
@@ -0,0 +1,139 @@
+// Package extract finds out what a file is by its content and turns text
+// files, PDFs and office documents into plain text a model can read.
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// ErrUnsupported is returned for binary files that have no text to extract.
+var ErrUnsupported = errors.New("binary files of this type are not supported")
+
+// Type returns the MIME type of a file, sniffed from its content. Office
+// documents are zip archives, their extension tells them apart.
+func Type(name string, data []byte) string {
+	typ := http.DetectContentType(data)
+	if typ == "application/zip" {
+		if office, ok := officeTypes[strings.ToLower(filepath.Ext(name))]; ok {
+			return office
+		}
+	}
+	return typ
+}
+
+// IsImage reports whether typ is an image format that models can read.
+func IsImage(typ string) bool {
+	switch typ {
+	case "image/png", "image/jpeg", "image/gif", "image/webp":
+		return true
+	}
+	return false
+}
+
+// Text returns the text of a text file, PDF or office document. typ is the
+// type returned by Type.
+func Text(typ string, data []byte) (string, error) {
+	switch {
+	case typ == "application/pdf":
+		return pdfText(data)
+	case officeText[typ] != nil:
+		return officeText[typ](data)
+	case utf8.Valid(data) && !bytes.ContainsRune(data, 0):
+		// covers text/plain and everything that looks like source code, json
+		// or markup, whatever was sniffed
+		return string(data), nil
+	default:
+		return "", ErrUnsupported
+	}
+}
+
+// pdfText returns the text of all pages of a PDF.
+func pdfText(data []byte) (text string, err error) {
+	// the parser panics on some broken files
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("failed to read PDF: %v", r)
+		}
+	}()
+	r, err := pdf.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", fmt.Errorf("failed to read PDF: %w", err)
+	}
+	plain, err := r.GetPlainText()
+	if err != nil {
+		return "", fmt.Errorf("failed to read PDF: %w", err)
+	}
+	b, err := io.ReadAll(io.LimitReader(plain, maxText))
+	if err != nil {
+		return "", fmt.Errorf("failed to read PDF: %w", err)
+	}
+	text = strings.TrimSpace(string(b))
+	if text == "" {
+		return "", errors.New("the PDF has no text, it may be scanned")
+	}
+	return text, nil
+}
+
+// maxText limits the text read from a document, and the size of all files
+// unpacked from an office document together.
+const maxText = 50 << 20
+
+// maxFiles limits the number of files in an office document.
+const maxFiles = 10000
+
+// errTooLarge is returned for office documents that unpack to more than
+// maxText, they may be zip bombs.
+var errTooLarge = fmt.Errorf("failed to read document: it unpacks to more than %d MB", maxText>>20)
+
+// readZip returns the files of the zip archive data for which keep is true.
+func readZip(data []byte, keep func(name string) bool) (map[string][]byte, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open document: %w", err)
+	}
+	if len(zr.File) > maxFiles {
+		return nil, fmt.Errorf("failed to open document: it has more than %d files", maxFiles)
+	}
+	files := map[string][]byte{}
+	left := int64(maxText)
+	for _, f := range zr.File {
+		if !keep(f.Name) {
+			continue
+		}
+		b, err := readZipFile(f, left)
+		if err != nil {
+			return nil, err
+		}
+		left -= int64(len(b))
+		files[f.Name] = b
+	}
+	return files, nil
+}
+
+// readZipFile unpacks f, which may be at most limit bytes large. The size in
+// the archive is not trusted, it may be a zip bomb.
+func readZipFile(f *zip.File, limit int64) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s of document: %w", f.Name, err)
+	}
+	defer rc.Close()
+	b, err := io.ReadAll(io.LimitReader(rc, limit+1))
+	if int64(len(b)) > limit {
+		return nil, errTooLarge
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s of document: %w", f.Name, err)
+	}
+	return b, nil
+}
@@ -0,0 +1,149 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// zipOf builds a zip archive with the given files.
+func zipOf(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestType(t *testing.T) {
+	doc := zipOf(t, map[string]string{"word/document.xml": "<w:document/>"})
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"notes.txt", []byte("hello"), "text/plain; charset=utf-8"},
+		{"image.jpg", []byte("hello"), "text/plain; charset=utf-8"},
+		{"report.docx", doc, docx},
+		{"REPORT.DOCX", doc, docx},
+		{"table.ods", doc, ods},
+		{"archive.zip", doc, "application/zip"},
+		{"doc.pdf", []byte("%PDF-1.4\n"), "application/pdf"},
+		{"pic.png", []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\x0dIHDR"), "image/png"},
+	}
+	for _, tt := range tests {
+		if got := Type(tt.name, tt.data); got != tt.want {
+			t.Errorf("Type(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestText(t *testing.T) {
+	tests := []struct {
+		name string
+		typ  string
+		data []byte
+		want string
+	}{
+		{"plain text", "text/plain; charset=utf-8", []byte("héllo\nworld"), "héllo\nworld"},
+		{"source code sniffed as something else", "text/html; charset=utf-8", []byte("<b>x</b>"), "<b>x</b>"},
+		{
+			name: "docx",
+			typ:  docx,
+			data: zipOf(t, map[string]string{"word/document.xml": `<w:document xmlns:w="w"><w:body>` +
+				`<w:p><w:r><w:t>Hello</w:t></w:r><w:r><w:tab/><w:t>world</w:t></w:r></w:p>` +
+				`<w:p><w:r><w:t>second</w:t></w:r></w:p></w:body></w:document>`}),
+			want: "Hello\tworld\nsecond",
+		},
+		{
+			name: "pptx slides in order",
+			typ:  pptx,
+			data: zipOf(t, map[string]string{
+				"ppt/slides/slide10.xml": `<p:sld xmlns:p="p" xmlns:a="a"><a:p><a:t>ten</a:t></a:p></p:sld>`,
+				"ppt/slides/slide2.xml":  `<p:sld xmlns:p="p" xmlns:a="a"><a:p><a:t>two</a:t></a:p></p:sld>`,
+				"ppt/slides/_rels/x.xml": `<x/>`,
+			}),
+			want: "Slide 2:\ntwo\n\nSlide 10:\nten",
+		},
+		{
+			name: "xlsx with shared and inline strings",
+			typ:  xlsx,
+			data: zipOf(t, map[string]string{
+				"xl/sharedStrings.xml": `<sst><si><t>name</t></si><si><r><t>Ad</t></r><r><t>a</t></r></si></sst>`,
+				"xl/worksheets/sheet1.xml": `<worksheet><sheetData>` +
+					`<row><c t="s"><v>0</v></c><c t="inlineStr"><is><t>age</t></is></c></row>` +
+					`<row><c t="s"><v>1</v></c><c><v>36</v></c></row>` +
+					`</sheetData></worksheet>`,
+			}),
+			want: "Sheet 1:\nname\tage\nAda\t36",
+		},
+		{
+			name: "odt",
+			typ:  odt,
+			data: zipOf(t, map[string]string{"content.xml": `<office:document-content xmlns:office="o" xmlns:text="t">` +
+				`<text:h>Title</text:h><text:p>a<text:s/>b<text:line-break/>c</text:p></office:document-content>`}),
+			want: "Title\na b\nc",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Text(tt.typ, tt.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Text() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTextErrors(t *testing.T) {
+	if _, err := Text("application/octet-stream", []byte{0, 1, 2}); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Text() of binary data error = %v, want ErrUnsupported", err)
+	}
+	if _, err := Text(docx, zipOf(t, map[string]string{"other.xml": "<x/>"})); err == nil {
+		t.Error("Text() of a docx without document.xml succeeded")
+	}
+	if _, err := Text(docx, []byte("not a zip")); err == nil {
+		t.Error("Text() of a broken docx succeeded")
+	}
+	if _, err := Text("application/pdf", []byte("%PDF-1.4 broken")); err == nil {
+		t.Error("Text() of a broken PDF succeeded")
+	}
+}
+
+func TestZipLimits(t *testing.T) {
+	// every slide is below the limit, together they are above it
+	slide := `<p:sld xmlns:a="a"><a:t>` + strings.Repeat(" ", maxText/4) + `</a:t></p:sld>`
+	files := map[string]string{}
+	for _, n := range []string{"1", "2", "3", "4", "5"} {
+		files["ppt/slides/slide"+n+".xml"] = slide
+	}
+	if _, err := Text(pptx, zipOf(t, files)); !errors.Is(err, errTooLarge) {
+		t.Errorf("Text() of an oversized pptx error = %v, want errTooLarge", err)
+	}
+
+	many := map[string]string{}
+	for i := range maxFiles + 1 {
+		many[fmt.Sprintf("file%d", i)] = ""
+	}
+	many["word/document.xml"] = "<w:document/>"
+	if _, err := Text(docx, zipOf(t, many)); err == nil || !strings.Contains(err.Error(), "files") {
+		t.Errorf("Text() of a docx with too many files error = %v", err)
+	}
+}
@@ -0,0 +1,256 @@
+package extract
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// MIME types of the office documents that Text understands.
+const (
+	docx = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+	xlsx = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	pptx = "application/vnd.openxmlformats-officedocument.presentationml.presentation"
+	odt  = "application/vnd.oasis.opendocument.text"
+	ods  = "application/vnd.oasis.opendocument.spreadsheet"
+	odp  = "application/vnd.oasis.opendocument.presentation"
+)
+
+var officeTypes = map[string]string{
+	".docx": docx,
+	".xlsx": xlsx,
+	".pptx": pptx,
+	".odt":  odt,
+	".ods":  ods,
+	".odp":  odp,
+}
+
+var officeText = map[string]func(data []byte) (string, error){
+	docx: docxText,
+	xlsx: xlsxText,
+	pptx: pptxText,
+	odt:  odfText,
+	ods:  odfText,
+	odp:  odfText,
+}
+
+// xmlText describes which parts of an XML document hold its text. Elements
+// are matched by their local name.
+type xmlText struct {
+	// keep are the elements whose text is kept, other text is whitespace
+	// between elements.
+	keep []string
+	// lines are the elements that end a line.
+	lines []string
+	// tabs and spaces are the elements that stand for a tab or a space.
+	tabs, spaces []string
+}
+
+func (x xmlText) read(data []byte) (string, error) {
+	var sb strings.Builder
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	depth := 0
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return strings.TrimSpace(sb.String()), nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to read document: %w", err)
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			name := tok.Name.Local
+			switch {
+			case slices.Contains(x.keep, name):
+				depth++
+			case slices.Contains(x.tabs, name):
+				sb.WriteByte('\t')
+			case slices.Contains(x.spaces, name):
+				sb.WriteByte(' ')
+			}
+		case xml.EndElement:
+			name := tok.Name.Local
+			if slices.Contains(x.keep, name) {
+				depth--
+			}
+			if slices.Contains(x.lines, name) {
+				sb.WriteByte('\n')
+			}
+		case xml.CharData:
+			if depth > 0 {
+				sb.Write(tok)
+			}
+		}
+	}
+}
+
+// wordprocessing documents and slides of presentations
+var ooxml = xmlText{keep: []string{"t"}, lines: []string{"p", "br"}, tabs: []string{"tab"}}
+
+func docxText(data []byte) (string, error) {
+	files, err := readZip(data, func(name string) bool { return name == "word/document.xml" })
+	if err != nil {
+		return "", err
+	}
+	doc, ok := files["word/document.xml"]
+	if !ok {
+		return "", errors.New("failed to read document: word/document.xml is missing")
+	}
+	return ooxml.read(doc)
+}
+
+func pptxText(data []byte) (string, error) {
+	files, err := readZip(data, func(name string) bool { return number(name, "ppt/slides/slide") > 0 })
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	for _, name := range numbered(files, "ppt/slides/slide") {
+		text, err := ooxml.read(files[name])
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&sb, "Slide %d:\n%s\n\n", number(name, "ppt/slides/slide"), text)
+	}
+	return strings.TrimSpace(sb.String()), nil
+}
+
+// xlsxText returns the sheets of a workbook, a line per row with the cells
+// separated by tabs.
+func xlsxText(data []byte) (string, error) {
+	files, err := readZip(data, func(name string) bool {
+		return name == "xl/sharedStrings.xml" || number(name, "xl/worksheets/sheet") > 0
+	})
+	if err != nil {
+		return "", err
+	}
+	var shared []string
+	if b, ok := files["xl/sharedStrings.xml"]; ok {
+		if shared, err = sharedStrings(b); err != nil {
+			return "", err
+		}
+	}
+
+	var sb strings.Builder
+	for _, name := range numbered(files, "xl/worksheets/sheet") {
+		text, err := sheetText(files[name], shared)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&sb, "Sheet %d:\n%s\n\n", number(name, "xl/worksheets/sheet"), text)
+	}
+	return strings.TrimSpace(sb.String()), nil
+}
+
+// sharedStrings returns the strings that cells of a workbook refer to by
+// their index.
+func sharedStrings(data []byte) ([]string, error) {
+	var sst struct {
+		Items []struct {
+			Text string `xml:"t"`
+			Runs []struct {
+				Text string `xml:"t"`
+			} `xml:"r"`
+		} `xml:"si"`
+	}
+	if err := xml.Unmarshal(data, &sst); err != nil {
+		return nil, fmt.Errorf("failed to read document: %w", err)
+	}
+	shared := make([]string, len(sst.Items))
+	for i, si := range sst.Items {
+		shared[i] = si.Text
+		for _, r := range si.Runs {
+			shared[i] += r.Text
+		}
+	}
+	return shared, nil
+}
+
+func sheetText(data []byte, shared []string) (string, error) {
+	var sheet struct {
+		Rows []struct {
+			Cells []struct {
+				Type   string `xml:"t,attr"`
+				Value  string `xml:"v"`
+				Inline string `xml:"is>t"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := xml.Unmarshal(data, &sheet); err != nil {
+		return "", fmt.Errorf("failed to read document: %w", err)
+	}
+	var sb strings.Builder
+	for _, row := range sheet.Rows {
+		cells := make([]string, len(row.Cells))
+		for i, c := range row.Cells {
+			switch c.Type {
+			case "s":
+				if n, err := strconv.Atoi(c.Value); err == nil && n >= 0 && n < len(shared) {
+					cells[i] = shared[n]
+				}
+			case "inlineStr":
+				cells[i] = c.Inline
+			default:
+				cells[i] = c.Value
+			}
+		}
+		sb.WriteString(strings.Join(cells, "\t") + "\n")
+	}
+	return strings.TrimSpace(sb.String()), nil
+}
+
+// odfText returns the text of an OpenDocument text, spreadsheet or
+// presentation.
+func odfText(data []byte) (string, error) {
+	files, err := readZip(data, func(name string) bool { return name == "content.xml" })
+	if err != nil {
+		return "", err
+	}
+	content, ok := files["content.xml"]
+	if !ok {
+		return "", errors.New("failed to read document: content.xml is missing")
+	}
+	odf := xmlText{
+		keep:   []string{"p", "h"},
+		lines:  []string{"p", "h", "line-break"},
+		tabs:   []string{"tab"},
+		spaces: []string{"s"},
+	}
+	return odf.read(content)
+}
+
+// number returns the number of a file like prefix + "12.xml", 0 for other
+// files.
+func number(name, prefix string) int {
+	s, ok := strings.CutPrefix(name, prefix)
+	if !ok {
+		return 0
+	}
+	s, ok = strings.CutSuffix(s, ".xml")
+	if !ok {
+		return 0
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// numbered returns the names of the numbered files in order.
+func numbered(files map[string][]byte, prefix string) []string {
+	var names []string
+	for name := range files {
+		if number(name, prefix) > 0 {
+			names = append(names, name)
+		}
+	}
+	slices.SortFunc(names, func(a, b string) int { return number(a, prefix) - number(b, prefix) })
+	return names
+}
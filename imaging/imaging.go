@@ -0,0 +1,75 @@
+// Package imaging prepares images for multimodal models.
+package imaging
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// DefaultMaxSize is the longest side in pixels images are downscaled to.
+// Vision models work on smaller tiles anyway, larger images only cost time.
+const DefaultMaxSize = 1568
+
+// MaxPixels limits the images that are accepted at all. A small file can
+// declare a huge size, decoding it would take gigabytes of memory.
+const MaxPixels = 50_000_000
+
+// Downscale shrinks an image whose longer side is above maxSize pixels, so
+// that side is maxSize long. Smaller images are returned as they are.
+// Downscaled images are PNGs if they may be transparent, JPEGs otherwise.
+// Images with more than MaxPixels pixels are rejected.
+func Downscale(data []byte, maxSize int) ([]byte, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image: %w", err)
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > MaxPixels {
+		return nil, fmt.Errorf("image is %dx%d pixels, at most %d megapixels are supported", cfg.Width, cfg.Height, MaxPixels/1_000_000)
+	}
+	if maxSize <= 0 || max(cfg.Width, cfg.Height) <= maxSize {
+		return data, nil
+	}
+
+	// animated gifs are reduced to their first frame
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s image: %w", format, err)
+	}
+	w, h := cfg.Width, cfg.Height
+	if w >= h {
+		w, h = maxSize, max(1, h*maxSize/w)
+	} else {
+		w, h = max(1, w*maxSize/h), maxSize
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, src.Bounds(), draw.Src, nil)
+
+	var buf bytes.Buffer
+	if opaque(format, src) {
+		err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 90})
+	} else {
+		err = png.Encode(&buf, dst)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode image: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// opaque reports whether an image can be stored without transparency.
+func opaque(format string, img image.Image) bool {
+	if format == "jpeg" {
+		return true
+	}
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		return o.Opaque()
+	}
+	return false
+}
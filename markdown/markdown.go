@@ -0,0 +1,119 @@
+package markdown
+
+import (
+	"bytes"
+	"html/template"
+	"regexp"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/util"
+)
+
+// style is the chroma style of code blocks.
+const style = "catppuccin-mocha"
+
+var (
+	formatter = chromahtml.New(chromahtml.WithClasses(true))
+
+	md = goldmark.New(
+		goldmark.WithExtensions(extension.GFM),
+		goldmark.WithRendererOptions(
+			renderer.WithNodeRenderers(util.Prioritized(codeRenderer{}, 100)),
+		),
+	)
+
+	// policy allows what markdown produces plus the classes of chroma. Raw
+	// HTML in answers is never rendered by goldmark, the policy is a second
+	// line of defense.
+	policy = func() *bluemonday.Policy {
+		p := bluemonday.UGCPolicy()
+		p.AllowAttrs("class").Matching(regexp.MustCompile(`^[\w\- ]+$`)).OnElements("span", "pre", "code", "div")
+		return p
+	}()
+)
+
+// HTML renders the markdown of a model answer to sanitized HTML with
+// highlighted code blocks.
+func HTML(text string) template.HTML {
+	var buf bytes.Buffer
+	if err := md.Convert([]byte(text), &buf); err != nil {
+		return template.HTML(template.HTMLEscapeString(text))
+	}
+	return template.HTML(policy.SanitizeBytes(buf.Bytes()))
+}
+
+// CSS returns the stylesheet of rendered answers and highlighted code.
+func CSS() string {
+	var buf strings.Builder
+	buf.WriteString(baseCSS)
+	formatter.WriteCSS(&buf, styles.Get(style))
+	return buf.String()
+}
+
+// baseCSS styles the elements of rendered answers, the page resets them.
+const baseCSS = `.markdown h1 { font-size: 1.5em; font-weight: bold; margin: 0.5em 0; }
+.markdown h2 { font-size: 1.3em; font-weight: bold; margin: 0.5em 0; }
+.markdown h3, .markdown h4, .markdown h5, .markdown h6 { font-weight: bold; margin: 0.5em 0; }
+.markdown p { margin: 0.5em 0; }
+.markdown ul { list-style: disc; padding-left: 1.5em; margin: 0.5em 0; }
+.markdown ol { list-style: decimal; padding-left: 1.5em; margin: 0.5em 0; }
+.markdown a { text-decoration: underline; }
+.markdown blockquote { border-left: 4px solid currentColor; padding-left: 1em; opacity: 0.8; margin: 0.5em 0; }
+.markdown :not(pre) > code { background: rgba(0, 0, 0, 0.1); padding: 0.1em 0.3em; border-radius: 4px; }
+.markdown table { border-collapse: collapse; margin: 0.5em 0; }
+.markdown th, .markdown td { border: 1px solid currentColor; padding: 0.25em 0.5em; }
+.markdown .code-block { position: relative; }
+.markdown pre { padding: 0.75em 1em; border-radius: 8px; overflow-x: auto; margin: 0.5em 0; }
+.markdown .copy-code { position: absolute; top: 0.4em; right: 0.4em; }
+`
+
+// codeRenderer highlights code blocks with chroma.
+type codeRenderer struct{}
+
+func (codeRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(ast.KindFencedCodeBlock, renderCode)
+	reg.Register(ast.KindCodeBlock, renderCode)
+}
+
+func renderCode(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+
+	var lang string
+	if block, ok := node.(*ast.FencedCodeBlock); ok {
+		lang = string(block.Language(source))
+	}
+	var code strings.Builder
+	lines := node.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		line := lines.At(i)
+		code.Write(line.Value(source))
+	}
+
+	lexer := lexers.Get(lang)
+	if lexer == nil {
+		lexer = lexers.Analyse(code.String())
+	}
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+	tokens, err := chroma.Coalesce(lexer).Tokenise(nil, code.String())
+	if err == nil {
+		err = formatter.Format(w, styles.Get(style), tokens)
+	}
+	if err != nil {
+		// fall back to a plain block, the answer is still readable
+		w.WriteString("<pre><code>" + template.HTMLEscapeString(code.String()) + "</code></pre>\n")
+	}
+	return ast.WalkSkipChildren, nil
+}
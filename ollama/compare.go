@@ -0,0 +1,74 @@
+package ollama
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Result is the answer of one model in a comparison.
+type Result struct {
+	Model  string
+	Answer string
+	// Latency is the time until the whole answer arrived, FirstToken the
+	// time until its first chunk arrived.
+	Latency    time.Duration
+	FirstToken time.Duration
+	Metrics
+	Err error
+}
+
+// MaxParallel is the number of models Compare asks at the same time, the
+// others wait for their turn. More would only compete for the same GPU.
+const MaxParallel = 4
+
+// Compare sends the requests, up to MaxParallel at the same time, and returns
+// the results in the order of reqs. onChunk, which may be nil, is called
+// whenever a piece of any answer arrives, never twice at the same time.
+func Compare(ctx context.Context, reqs []*Ollama, onChunk func()) []Result {
+	results := make([]Result, len(reqs))
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	slots := make(chan struct{}, MaxParallel)
+	for i, req := range reqs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			start := time.Now()
+			var first time.Duration
+			answer, metrics, err := Chat(ctx, req, func(string) {
+				if first == 0 {
+					first = time.Since(start)
+				}
+				if onChunk != nil {
+					mu.Lock()
+					onChunk()
+					mu.Unlock()
+				}
+			})
+			results[i] = Result{
+				Model:      req.Model,
+				Answer:     answer,
+				Latency:    time.Since(start),
+				FirstToken: first,
+				Metrics:    metrics,
+				Err:        err,
+			}
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// Stats sums up the latency and token counts of r.
+func (r Result) Stats() string {
+	if r.Err != nil {
+		return fmt.Sprintf("failed after %s", r.Latency.Round(time.Millisecond))
+	}
+	return fmt.Sprintf("%s, first token %s, %d+%d tokens, %.1f tok/s",
+		r.Latency.Round(time.Millisecond), r.FirstToken.Round(time.Millisecond),
+		r.PromptEvalCount, r.EvalCount, r.TokensPerSecond())
+}
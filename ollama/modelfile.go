@@ -0,0 +1,182 @@
+package ollama
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Modelfile holds the instructions of an ollama Modelfile.
+type Modelfile struct {
+	From       string
+	System     string
+	Template   string
+	License    string
+	Parameters map[string]any
+	Messages   []Message
+}
+
+// ParseModelfile reads a Modelfile. Values can be given inline, in double
+// quotes or in triple quotes spanning multiple lines.
+func ParseModelfile(r io.Reader) (*Modelfile, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	mf := &Modelfile{}
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		lineNo := i + 1
+
+		instruction, rest, _ := strings.Cut(line, " ")
+		rest = strings.TrimSpace(rest)
+
+		switch strings.ToUpper(instruction) {
+		case "FROM":
+			mf.From, err = readValue(rest, lines, &i)
+		case "SYSTEM":
+			mf.System, err = readValue(rest, lines, &i)
+		case "TEMPLATE":
+			mf.Template, err = readValue(rest, lines, &i)
+		case "LICENSE":
+			mf.License, err = readValue(rest, lines, &i)
+		case "PARAMETER":
+			key, value, ok := strings.Cut(rest, " ")
+			if !ok {
+				return nil, fmt.Errorf("line %d: PARAMETER needs a name and a value", lineNo)
+			}
+			value, err = readValue(strings.TrimSpace(value), lines, &i)
+			mf.SetParameter(key, value)
+		case "MESSAGE":
+			role, content, ok := strings.Cut(rest, " ")
+			if !ok {
+				return nil, fmt.Errorf("line %d: MESSAGE needs a role and a content", lineNo)
+			}
+			if !slices.Contains([]string{"system", "user", "assistant"}, role) {
+				return nil, fmt.Errorf("line %d: unknown message role %q", lineNo, role)
+			}
+			content, err = readValue(strings.TrimSpace(content), lines, &i)
+			mf.Messages = append(mf.Messages, Message{Role: role, Content: content})
+		case "ADAPTER":
+			return nil, fmt.Errorf("line %d: ADAPTER is not supported, use 'ollama create' instead", lineNo)
+		default:
+			return nil, fmt.Errorf("line %d: unknown instruction %q", lineNo, instruction)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+	}
+
+	if mf.From == "" {
+		return nil, fmt.Errorf("no FROM instruction found")
+	}
+	if isLocalPath(mf.From) {
+		return nil, fmt.Errorf("creating from local file %s is not supported, use 'ollama create' instead", mf.From)
+	}
+	return mf, nil
+}
+
+// SetParameter adds a parameter, converting it to a number or boolean when
+// possible. 'stop' may be given several times and is collected into a list.
+func (mf *Modelfile) SetParameter(key string, value string) {
+	if mf.Parameters == nil {
+		mf.Parameters = map[string]any{}
+	}
+	if key == "stop" {
+		stops, _ := mf.Parameters[key].([]string)
+		mf.Parameters[key] = append(stops, value)
+		return
+	}
+	if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+		mf.Parameters[key] = i
+	} else if f, err := strconv.ParseFloat(value, 64); err == nil {
+		mf.Parameters[key] = f
+	} else if b, err := strconv.ParseBool(value); err == nil {
+		mf.Parameters[key] = b
+	} else {
+		mf.Parameters[key] = value
+	}
+}
+
+// String renders the Modelfile in ollama's text format.
+func (mf *Modelfile) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "FROM %s\n", mf.From)
+	if mf.System != "" {
+		fmt.Fprintf(&sb, "SYSTEM \"\"\"%s\"\"\"\n", mf.System)
+	}
+	if mf.Template != "" {
+		fmt.Fprintf(&sb, "TEMPLATE \"\"\"%s\"\"\"\n", mf.Template)
+	}
+
+	keys := make([]string, 0, len(mf.Parameters))
+	for k := range mf.Parameters {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		if stops, ok := mf.Parameters[k].([]string); ok {
+			for _, s := range stops {
+				fmt.Fprintf(&sb, "PARAMETER %s %q\n", k, s)
+			}
+			continue
+		}
+		fmt.Fprintf(&sb, "PARAMETER %s %v\n", k, mf.Parameters[k])
+	}
+
+	for _, m := range mf.Messages {
+		fmt.Fprintf(&sb, "MESSAGE %s %q\n", m.Role, m.Content)
+	}
+	if mf.License != "" {
+		fmt.Fprintf(&sb, "LICENSE \"\"\"%s\"\"\"\n", mf.License)
+	}
+	return sb.String()
+}
+
+// readValue returns the value of an instruction. A value starting with """
+// continues over the following lines until the closing """, in which case
+// i is advanced to the last line consumed.
+func readValue(s string, lines []string, i *int) (string, error) {
+	if !strings.HasPrefix(s, `"""`) {
+		return unquote(s), nil
+	}
+	value := s[3:]
+	for !strings.Contains(value, `"""`) {
+		*i++
+		if *i >= len(lines) {
+			return "", fmt.Errorf("unterminated \"\"\"")
+		}
+		value += "\n" + lines[*i]
+	}
+	end := strings.Index(value, `"""`)
+	// a value ending in a quote is closed by the last three quotes
+	for end+3 < len(value) && value[end+3] == '"' {
+		end++
+	}
+	return value[:end], nil
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && strings.HasPrefix(s, `"`) && strings.HasSuffix(s, `"`) {
+		if u, err := strconv.Unquote(s); err == nil {
+			return u
+		}
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+func isLocalPath(from string) bool {
+	if strings.HasPrefix(from, ".") || strings.HasPrefix(from, "~") || filepath.IsAbs(from) {
+		return true
+	}
+	return strings.HasSuffix(strings.ToLower(from), ".gguf")
+}
@@ -0,0 +1,144 @@
+package ollama
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseModelfile(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  *Modelfile
+	}{
+		{
+			name:  "inline values",
+			input: "FROM llama3\nSYSTEM You are a pirate.\n",
+			want:  &Modelfile{From: "llama3", System: "You are a pirate."},
+		},
+		{
+			name:  "comments, blank lines and lower case instructions",
+			input: "# my model\n\nfrom llama3\n  # indented comment\nsystem hi\n",
+			want:  &Modelfile{From: "llama3", System: "hi"},
+		},
+		{
+			name:  "double quotes",
+			input: "FROM llama3\nSYSTEM \"Say \\\"hi\\\".\"\n",
+			want:  &Modelfile{From: "llama3", System: `Say "hi".`},
+		},
+		{
+			name:  "triple quotes on one line",
+			input: "FROM llama3\nTEMPLATE \"\"\"{{ .Prompt }}\"\"\"\n",
+			want:  &Modelfile{From: "llama3", Template: "{{ .Prompt }}"},
+		},
+		{
+			name:  "triple quotes over several lines",
+			input: "FROM llama3\nSYSTEM \"\"\"first\nsecond\n\nthird\"\"\"\nPARAMETER seed 1\n",
+			want: &Modelfile{
+				From:       "llama3",
+				System:     "first\nsecond\n\nthird",
+				Parameters: map[string]any{"seed": int64(1)},
+			},
+		},
+		{
+			name:  "windows line endings",
+			input: "FROM llama3\r\nSYSTEM \"\"\"a\r\nb\"\"\"\r\n",
+			want:  &Modelfile{From: "llama3", System: "a\nb"},
+		},
+		{
+			name: "parameter types",
+			input: "FROM llama3\n" +
+				"PARAMETER num_ctx 4096\n" +
+				"PARAMETER temperature 0.7\n" +
+				"PARAMETER use_mmap false\n" +
+				"PARAMETER stop \"<|end|>\"\n" +
+				"PARAMETER stop User:\n",
+			want: &Modelfile{
+				From: "llama3",
+				Parameters: map[string]any{
+					"num_ctx":     int64(4096),
+					"temperature": 0.7,
+					"use_mmap":    false,
+					"stop":        []string{"<|end|>", "User:"},
+				},
+			},
+		},
+		{
+			name:  "messages",
+			input: "FROM llama3\nMESSAGE user \"Is the sky blue?\"\nMESSAGE assistant yes\n",
+			want: &Modelfile{
+				From: "llama3",
+				Messages: []Message{
+					{Role: "user", Content: "Is the sky blue?"},
+					{Role: "assistant", Content: "yes"},
+				},
+			},
+		},
+		{
+			name:  "license and registry model",
+			input: "FROM registry.local:5000/team/llama3:8b\nLICENSE \"\"\"MIT\"\"\"\n",
+			want:  &Modelfile{From: "registry.local:5000/team/llama3:8b", License: "MIT"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseModelfile(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("ParseModelfile() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseModelfile() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseModelfileErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"no from", "SYSTEM hi\n", "no FROM instruction found"},
+		{"unknown instruction", "FROM llama3\nFOO bar\n", `line 2: unknown instruction "FOO"`},
+		{"parameter without value", "FROM llama3\nPARAMETER seed\n", "line 2: PARAMETER needs a name and a value"},
+		{"message without content", "FROM llama3\nMESSAGE user\n", "line 2: MESSAGE needs a role and a content"},
+		{"unknown role", "FROM llama3\nMESSAGE tool hi\n", `line 2: unknown message role "tool"`},
+		{"unterminated triple quotes", "FROM llama3\nSYSTEM \"\"\"open\nstill open\n", `line 2: unterminated """`},
+		{"adapter", "FROM llama3\nADAPTER ./lora.gguf\n", "line 2: ADAPTER is not supported"},
+		{"relative path", "FROM ./model.gguf\n", "creating from local file ./model.gguf is not supported"},
+		{"absolute path", "FROM /models/llama\n", "creating from local file /models/llama is not supported"},
+		{"gguf file", "FROM model.GGUF\n", "creating from local file model.GGUF is not supported"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseModelfile(strings.NewReader(tt.input))
+			if err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+				t.Errorf("ParseModelfile() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestModelfileStringRoundTrip(t *testing.T) {
+	mf := &Modelfile{
+		From:     "llama3:latest",
+		System:   "line one\nline \"two\"",
+		Template: "{{ .Prompt }}",
+		License:  "MIT",
+		Parameters: map[string]any{
+			"num_ctx":     int64(2048),
+			"temperature": 0.2,
+			"stop":        []string{"a b", "\"q\""},
+		},
+		Messages: []Message{{Role: "user", Content: "hi\nthere"}},
+	}
+	got, err := ParseModelfile(strings.NewReader(mf.String()))
+	if err != nil {
+		t.Fatalf("ParseModelfile(String()) error = %v\n%s", err, mf.String())
+	}
+	if !reflect.DeepEqual(got, mf) {
+		t.Errorf("round trip = %#v, want %#v", got, mf)
+	}
+}
@@ -3,13 +3,15 @@ package ollama
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
-	"os/exec"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -18,7 +20,22 @@ import (
 	"golang.org/x/net/html"
 )
 
-const ollama_api = "http://localhost:11434/api/chat"
+// DefaultHost is the address ollama listens on by default.
+const DefaultHost = "http://localhost:11434"
+
+var host = DefaultHost
+
+// SetHost changes the address of the ollama server used by all requests.
+func SetHost(h string) {
+	if h == "" {
+		h = DefaultHost
+	}
+	host = strings.TrimSuffix(h, "/")
+}
+
+func endpoint(path string) string {
+	return host + path
+}
 
 type Message struct {
 	Role    string   `json:"role"`
@@ -27,25 +44,100 @@ type Message struct {
 }
 
 type Ollama struct {
-	Model    string    `json:"model"`
-	Messages []Message `json:"messages"`
-	Stream   bool      `json:"stream"`
+	Model     string         `json:"model"`
+	Messages  []Message      `json:"messages"`
+	Stream    bool           `json:"stream"`
+	KeepAlive string         `json:"keep_alive,omitempty"`
+	Options   map[string]any `json:"options,omitempty"`
 }
 
 type Response struct {
-	Resp Message `json:"message"`
+	Resp  Message `json:"message"`
+	Done  bool    `json:"done"`
+	Error string  `json:"error"`
+	Metrics
+}
+
+// Metrics are the token counts and durations ollama sends with the last
+// chunk of an answer.
+type Metrics struct {
+	TotalDuration   time.Duration `json:"total_duration"`
+	LoadDuration    time.Duration `json:"load_duration"`
+	PromptEvalCount int           `json:"prompt_eval_count"`
+	EvalCount       int           `json:"eval_count"`
+	EvalDuration    time.Duration `json:"eval_duration"`
+	// DoneReason is why the answer ended, e.g. "stop" or "length".
+	DoneReason string `json:"done_reason"`
+}
+
+// TokensPerSecond is the speed the answer was generated with.
+func (m Metrics) TokensPerSecond() float64 {
+	if m.EvalDuration <= 0 {
+		return 0
+	}
+	return float64(m.EvalCount) / m.EvalDuration.Seconds()
 }
 
 type PullResponse struct {
 	Status    string `json:"status"`
+	Digest    string `json:"digest"`
 	Total     int64  `json:"total"`
 	Completed int64  `json:"completed"`
+	Error     string `json:"error"`
+}
+
+// RunningModel is a model that is currently loaded into memory.
+type RunningModel struct {
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	SizeVRAM  int64     `json:"size_vram"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Details   struct {
+		ParameterSize     string `json:"parameter_size"`
+		QuantizationLevel string `json:"quantization_level"`
+	} `json:"details"`
+}
+
+type createRequest struct {
+	Model      string         `json:"model"`
+	From       string         `json:"from"`
+	System     string         `json:"system,omitempty"`
+	Template   string         `json:"template,omitempty"`
+	License    string         `json:"license,omitempty"`
+	Parameters map[string]any `json:"parameters,omitempty"`
+	Messages   []Message      `json:"messages,omitempty"`
+	Stream     bool           `json:"stream"`
 }
 
 func NewOllama() *Ollama {
 	return &Ollama{}
 }
 
+// TrimHistory keeps the system messages and at most the last max other
+// messages. A max of 0 keeps everything. The kept messages start with a
+// prompt, so an answer is never sent without the prompt it belongs to.
+func TrimHistory(messages []Message, max int) []Message {
+	if max <= 0 {
+		return messages
+	}
+	var system, rest []Message
+	for _, m := range messages {
+		if m.Role == "system" {
+			system = append(system, m)
+		} else {
+			rest = append(rest, m)
+		}
+	}
+	if len(rest) <= max {
+		return messages
+	}
+	start := len(rest) - max
+	for start < len(rest)-1 && rest[start].Role != "user" {
+		start++
+	}
+	return append(system, rest[start:]...)
+}
+
 const bufferSize = 1024 * 1024 // 1 MB
 
 func GetResponse(ollama *Ollama) (string, error) {
@@ -56,7 +148,7 @@ func GetResponse(ollama *Ollama) (string, error) {
 	}
 
 	c := http.Client{Timeout: time.Minute * 10}
-	resp, err := c.Post(ollama_api, "application/json", body)
+	resp, err := c.Post(endpoint("/api/chat"), "application/json", body)
 	if err != nil {
 		return "", fmt.Errorf("post request to ollama api failed: %w", err)
 	}
@@ -91,7 +183,123 @@ func GetResponse(ollama *Ollama) (string, error) {
 	return clean(aiResponse), nil
 }
 
+// Chat sends req and streams the answer to onChunk, which may be nil. Unlike
+// GetResponse it prints nothing, so several chats can run at the same time.
+func Chat(ctx context.Context, req *Ollama, onChunk func(chunk string)) (string, Metrics, error) {
+	body := new(bytes.Buffer)
+	r := *req
+	r.Stream = true
+	if err := json.NewEncoder(body).Encode(&r); err != nil {
+		return "", Metrics{}, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint("/api/chat"), body)
+	if err != nil {
+		return "", Metrics{}, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		return "", Metrics{}, fmt.Errorf("post request to ollama api failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(resp.Body)
+		return "", Metrics{}, fmt.Errorf("ollama api returned status %d: %s", resp.StatusCode, string(b))
+	}
+
+	var answer strings.Builder
+	var metrics Metrics
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, bufferSize), bufferSize)
+	for scanner.Scan() {
+		bts := scanner.Bytes()
+		if len(bts) == 0 {
+			continue
+		}
+		var chunk Response
+		if err := json.Unmarshal(bts, &chunk); err != nil {
+			return "", Metrics{}, fmt.Errorf("failed to unmarshal response: %w", err)
+		}
+		if chunk.Error != "" {
+			return "", Metrics{}, fmt.Errorf("ollama: %s", chunk.Error)
+		}
+		if chunk.Resp.Content != "" {
+			answer.WriteString(chunk.Resp.Content)
+			if onChunk != nil {
+				onChunk(chunk.Resp.Content)
+			}
+		}
+		if chunk.Done {
+			metrics = chunk.Metrics
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", Metrics{}, fmt.Errorf("failed to read response: %w", err)
+	}
+	return clean(answer.String()), metrics, nil
+}
+
+// EmbedRequest asks for the embeddings of one or more inputs.
+type EmbedRequest struct {
+	Model     string         `json:"model"`
+	Input     []string       `json:"input"`
+	KeepAlive string         `json:"keep_alive,omitempty"`
+	Options   map[string]any `json:"options,omitempty"`
+}
+
+// EmbedResponse holds one embedding per input.
+type EmbedResponse struct {
+	Model           string      `json:"model"`
+	Embeddings      [][]float32 `json:"embeddings"`
+	PromptEvalCount int         `json:"prompt_eval_count"`
+}
+
+// Embed returns the embeddings of the inputs of req.
+func Embed(ctx context.Context, req *EmbedRequest) (*EmbedResponse, error) {
+	body := new(bytes.Buffer)
+	if err := json.NewEncoder(body).Encode(req); err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint("/api/embed"), body)
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("post request to ollama api failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("ollama api returned status %d: %s", resp.StatusCode, string(b))
+	}
+	var embeddings EmbedResponse
+	if err := json.NewDecoder(resp.Body).Decode(&embeddings); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &embeddings, nil
+}
+
 func PullModel(model string) error {
+	return pullModel(model, fmt.Sprintf("Pulling %s", model))
+}
+
+// PullModels pulls the given models one after another. Every progress bar is
+// prefixed with the position of the model in the batch, so the output reads as
+// one combined run. The returned slice holds one error per model (nil on success).
+func PullModels(models []string) []error {
+	errs := make([]error, len(models))
+	for i, model := range models {
+		errs[i] = pullModel(model, fmt.Sprintf("(%d/%d) Pulling %s", i+1, len(models), model))
+	}
+	return errs
+}
+
+func pullModel(model string, desc string) error {
 	models := ListModels()
 	reg := regexp.MustCompile(`:\w+\.?(\w+)?`)
 	modelname := reg.ReplaceAllString(model, "")
@@ -105,7 +313,7 @@ func PullModel(model string) error {
 				return fmt.Errorf("failed to encode model request: %w", err)
 			}
 			c := http.Client{Timeout: time.Minute * 10}
-			resp, err := c.Post("http://localhost:11434/api/pull", "application/json", body)
+			resp, err := c.Post(endpoint("/api/pull"), "application/json", body)
 			if err != nil {
 				return fmt.Errorf("post request to ollama api failed: %w", err)
 			}
@@ -133,7 +341,7 @@ func PullModel(model string) error {
 				}
 			}
 
-			bar := createPullProgressBar(pullResp.Total, model)
+			bar := createPullProgressBar(pullResp.Total, desc)
 
 			for scanner.Scan() {
 				bts := scanner.Bytes()
@@ -155,102 +363,382 @@ func PullModel(model string) error {
 	return fmt.Errorf("Model %s not found in the list of available models.", model)
 }
 
-func IsOllamaRunning() bool {
-	resp, err := http.Get("http://localhost:11434")
+// CreateModel creates the model name from the given Modelfile and prints the
+// progress reported by ollama.
+func CreateModel(name string, mf *Modelfile) error {
+	req := createRequest{
+		Model:      name,
+		From:       mf.From,
+		System:     mf.System,
+		Template:   mf.Template,
+		License:    mf.License,
+		Parameters: mf.Parameters,
+		Messages:   mf.Messages,
+		Stream:     true,
+	}
+	body := new(bytes.Buffer)
+	if err := json.NewEncoder(body).Encode(req); err != nil {
+		return fmt.Errorf("failed to encode create request: %w", err)
+	}
+
+	c := http.Client{Timeout: time.Minute * 10}
+	resp, err := c.Post(endpoint("/api/create"), "application/json", body)
 	if err != nil {
-		return false
+		return fmt.Errorf("post request to ollama api failed: %w", err)
 	}
 	defer resp.Body.Close()
-	return resp.StatusCode == 200
+
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("ollama api returned status %d: %s", resp.StatusCode, string(b))
+	}
+
+	return streamStatus(resp.Body, fmt.Sprintf("Creating %s", name))
+}
+
+// streamStatus follows a streamed status response of the ollama api until it
+// reports success. Status updates with a size get a progress bar, all other
+// updates are printed once.
+func streamStatus(r io.Reader, desc string) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, bufferSize), bufferSize)
+
+	var bar *progressbar.ProgressBar
+	var digest, last string
+	for scanner.Scan() {
+		bts := scanner.Bytes()
+		if len(bts) == 0 {
+			continue
+		}
+		var status PullResponse
+		if err := json.Unmarshal(bts, &status); err != nil {
+			return fmt.Errorf("failed to unmarshal status response: %w", err)
+		}
+		if status.Error != "" {
+			return fmt.Errorf("%s", status.Error)
+		}
+
+		if status.Total > 0 {
+			if bar == nil || status.Digest != digest {
+				if bar != nil {
+					bar.Finish()
+				}
+				bar = createPullProgressBar(status.Total, desc)
+				digest = status.Digest
+			}
+			bar.Set64(status.Completed)
+			continue
+		}
+		if bar != nil {
+			bar.Finish()
+			bar = nil
+		}
+
+		if status.Status == "success" {
+			return nil
+		}
+		if status.Status != last {
+			fmt.Println("[Msg] " + status.Status)
+			last = status.Status
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read status response: %w", err)
+	}
+	return fmt.Errorf("%s did not finish successfully", strings.ToLower(desc))
+}
+
+// CopyModel copies the model src to dst, e.g. to give it a new tag.
+func CopyModel(src string, dst string) error {
+	m := map[string]string{
+		"source":      src,
+		"destination": dst,
+	}
+	body := new(bytes.Buffer)
+	if err := json.NewEncoder(body).Encode(m); err != nil {
+		return fmt.Errorf("failed to encode copy request: %w", err)
+	}
+
+	c := http.Client{Timeout: time.Minute}
+	resp, err := c.Post(endpoint("/api/copy"), "application/json", body)
+	if err != nil {
+		return fmt.Errorf("post request to ollama api failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("ollama api returned status %d: %s", resp.StatusCode, string(b))
+	}
+	return nil
 }
 
-func Show(model string) (string, error) {
-	cmd := exec.Command("ollama", "show", model)
-	out, err := cmd.Output()
+// PushModel uploads a model to its registry. Set insecure to push to a
+// registry that is served over plain http.
+func PushModel(model string, insecure bool) error {
+	m := map[string]any{
+		"model":    model,
+		"insecure": insecure,
+		"stream":   true,
+	}
+	body := new(bytes.Buffer)
+	if err := json.NewEncoder(body).Encode(m); err != nil {
+		return fmt.Errorf("failed to encode push request: %w", err)
+	}
+
+	c := http.Client{Timeout: time.Minute * 10}
+	resp, err := c.Post(endpoint("/api/push"), "application/json", body)
 	if err != nil {
-		return "", fmt.Errorf("failed to run 'ollama show %s': %w", model, err)
+		return fmt.Errorf("post request to ollama api failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("ollama api returned status %d: %s", resp.StatusCode, string(b))
 	}
 
-	lines := strings.Split(string(out), "\n")
-	if len(lines) < 2 {
-		return "", fmt.Errorf("no information found for model %s", model)
+	return streamStatus(resp.Body, fmt.Sprintf("Pushing %s", model))
+}
+
+// RunningModels returns the models that are currently loaded into memory.
+func RunningModels() ([]RunningModel, error) {
+	c := http.Client{Timeout: time.Minute}
+	resp, err := c.Get(endpoint("/api/ps"))
+	if err != nil {
+		return nil, fmt.Errorf("get request to ollama api failed: %w", err)
 	}
+	defer resp.Body.Close()
 
-	var info []string
-	for _, line := range lines {
-		if strings.TrimSpace(line) == "" {
-			continue
-		}
-		info = append(info, line)
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("ollama api returned status %d: %s", resp.StatusCode, string(b))
 	}
 
-	return strings.Join(info, "\n"), nil
+	var ps struct {
+		Models []RunningModel `json:"models"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&ps); err != nil {
+		return nil, fmt.Errorf("failed to decode running models: %w", err)
+	}
+	return ps.Models, nil
 }
 
-func ListLocalModels() {
-	cmd := exec.Command("ollama", "list")
-	out, err := cmd.Output()
+// StopModel unloads a model from memory by sending an empty chat with a
+// keep_alive of zero.
+func StopModel(model string) error {
+	req := Ollama{
+		Model:     model,
+		Messages:  []Message{},
+		KeepAlive: "0s",
+	}
+	body := new(bytes.Buffer)
+	if err := json.NewEncoder(body).Encode(req); err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	c := http.Client{Timeout: time.Minute}
+	resp, err := c.Post(endpoint("/api/chat"), "application/json", body)
 	if err != nil {
-		message := "[Error] Could not run 'ollama list'!"
-		fmt.Println(message)
-		os.Exit(1)
+		return fmt.Errorf("post request to ollama api failed: %w", err)
 	}
+	defer resp.Body.Close()
 
-	lines := strings.Split(string(out), "\n")
-	if len(lines) < 2 {
-		message := "[Hint] No models found!"
-		fmt.Println(message)
-		return
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("ollama api returned status %d: %s", resp.StatusCode, string(b))
 	}
+	return nil
+}
 
-	var rows []string
+// ParseKeepAlive validates a keep_alive value. Plain numbers are taken as
+// seconds, a negative value keeps the model loaded forever.
+func ParseKeepAlive(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", nil
+	}
+	if _, err := strconv.Atoi(s); err == nil {
+		return s + "s", nil
+	}
+	if _, err := time.ParseDuration(s); err != nil {
+		return "", fmt.Errorf("invalid keep_alive %q, use a duration like 5m or 1h", s)
+	}
+	return s, nil
+}
 
-	header := lines[0]
-	if len(header) < 65 {
-		header = header + strings.Repeat(" ", 65-len(header))
+func IsOllamaRunning() bool {
+	resp, err := http.Get(host)
+	if err != nil {
+		return false
 	}
-	rows = append(rows, header)
-	rows = append(rows, strings.Repeat("-", 65))
+	defer resp.Body.Close()
+	return resp.StatusCode == 200
+}
 
-	for _, line := range lines[1:] {
-		if strings.TrimSpace(line) == "" {
-			continue
-		}
-		if len(line) < 65 {
-			line = line + strings.Repeat(" ", 65-len(line))
-		}
-		rows = append(rows, line)
+// ModelDetails is the information ollama has about a local model.
+type ModelDetails struct {
+	License      string         `json:"license"`
+	Modelfile    string         `json:"modelfile"`
+	Parameters   string         `json:"parameters"`
+	Template     string         `json:"template"`
+	System       string         `json:"system"`
+	Capabilities []string       `json:"capabilities"`
+	ModelInfo    map[string]any `json:"model_info"`
+	Details      struct {
+		Format            string `json:"format"`
+		Family            string `json:"family"`
+		ParameterSize     string `json:"parameter_size"`
+		QuantizationLevel string `json:"quantization_level"`
+	} `json:"details"`
+}
+
+// Architecture returns the architecture from the model info, falling back to
+// the model family.
+func (d *ModelDetails) Architecture() string {
+	if arch, ok := d.ModelInfo["general.architecture"].(string); ok {
+		return arch
 	}
+	return d.Details.Family
+}
+
+// ContextLength returns the context length the model was trained with, or 0
+// if ollama does not report it.
+func (d *ModelDetails) ContextLength() int64 {
+	return d.archInfo("context_length")
+}
+
+// EmbeddingLength returns the size of the model's embeddings, or 0 if ollama
+// does not report it.
+func (d *ModelDetails) EmbeddingLength() int64 {
+	return d.archInfo("embedding_length")
+}
 
-	table := strings.Join(rows, "\n")
-	fmt.Println(table)
+func (d *ModelDetails) archInfo(key string) int64 {
+	if v, ok := d.ModelInfo[d.Architecture()+"."+key].(float64); ok {
+		return int64(v)
+	}
+	return 0
 }
 
+func Show(model string) (*ModelDetails, error) {
+	m := map[string]string{
+		"model": model,
+	}
+	body := new(bytes.Buffer)
+	if err := json.NewEncoder(body).Encode(m); err != nil {
+		return nil, fmt.Errorf("failed to encode show request: %w", err)
+	}
+
+	c := http.Client{Timeout: time.Minute}
+	resp, err := c.Post(endpoint("/api/show"), "application/json", body)
+	if err != nil {
+		return nil, fmt.Errorf("post request to ollama api failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("ollama api returned status %d: %s", resp.StatusCode, string(b))
+	}
+
+	var details ModelDetails
+	if err := json.NewDecoder(resp.Body).Decode(&details); err != nil {
+		return nil, fmt.Errorf("failed to decode model information: %w", err)
+	}
+	return &details, nil
+}
+
+// RemoveModel deletes a model from the ollama server.
 func RemoveModel(model string) error {
-	cmd := exec.Command("ollama", "rm", model)
-	out, err := cmd.CombinedOutput()
+	body := new(bytes.Buffer)
+	if err := json.NewEncoder(body).Encode(map[string]string{"model": model}); err != nil {
+		return fmt.Errorf("failed to encode delete request: %w", err)
+	}
+	req, err := http.NewRequest(http.MethodDelete, endpoint("/api/delete"), body)
 	if err != nil {
-		return fmt.Errorf("failed to remove model %s: %s", model, string(out))
+		return fmt.Errorf("failed to create delete request: %w", err)
 	}
+	req.Header.Set("Content-Type", "application/json")
+
+	c := http.Client{Timeout: time.Minute}
+	resp, err := c.Do(req)
+	if err != nil {
+		return fmt.Errorf("delete request to ollama api failed: %w", err)
+	}
+	defer resp.Body.Close()
 
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("failed to remove model %s: ollama api returned status %d: %s", model, resp.StatusCode, string(b))
+	}
 	return nil
 }
 
-func IsModelPresent(model string) bool {
-	cmd := exec.Command("ollama", "list")
-	out, err := cmd.Output()
+// LocalModel is a model installed on the ollama server.
+type LocalModel struct {
+	Name       string    `json:"name"`
+	Digest     string    `json:"digest"`
+	Size       int64     `json:"size"`
+	ModifiedAt time.Time `json:"modified_at"`
+}
+
+// InstalledModels returns the models installed on the ollama server, the
+// most recently changed first.
+func InstalledModels() ([]LocalModel, error) {
+	c := http.Client{Timeout: time.Minute}
+	resp, err := c.Get(endpoint("/api/tags"))
 	if err != nil {
-		fmt.Println("[Error] Could not run 'ollama list'!")
+		return nil, fmt.Errorf("get request to ollama api failed: %w", err)
 	}
+	defer resp.Body.Close()
 
-	table := strings.Split(string(out), "\n")[1:] // Skip the header line
-	for _, line := range table {
-		present := strings.Contains(line, model)
-		if present {
-			return true
-		}
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("ollama api returned status %d: %s", resp.StatusCode, string(b))
+	}
+
+	var tags struct {
+		Models []LocalModel `json:"models"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tags); err != nil {
+		return nil, fmt.Errorf("failed to decode local models: %w", err)
+	}
+	return tags.Models, nil
+}
+
+// LocalModels returns the names of all locally installed models.
+func LocalModels() ([]string, error) {
+	models, err := InstalledModels()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(models))
+	for _, m := range models {
+		names = append(names, m.Name)
+	}
+	return names, nil
+}
+
+// FullName adds the tag ollama uses for local models to names like "llama3".
+// The name is kept as it is otherwise, names with a namespace or registry like
+// "registry.local:5000/team/llama3" only get the missing tag.
+func FullName(name string) string {
+	if !strings.Contains(name[strings.LastIndex(name, "/")+1:], ":") {
+		return name + ":latest"
+	}
+	return name
+}
+
+// IsModelPresent reports whether the model with exactly this name, tag
+// included, is installed.
+func IsModelPresent(model string) bool {
+	models, err := LocalModels()
+	if err != nil {
+		fmt.Println("[Error] Could not list local models: " + err.Error())
+		return false
 	}
-	return false
+	return slices.Contains(models, model)
 }
 
 type ModelInfo struct {
@@ -411,7 +899,7 @@ func StartSpinner(message string) func() {
 	return func() { close(done) }
 }
 
-func createPullProgressBar(total int64, model string) *progressbar.ProgressBar {
+func createPullProgressBar(total int64, desc string) *progressbar.ProgressBar {
 	bar := progressbar.NewOptions64(total,
 		progressbar.OptionSetWriter(os.Stdout),
 		progressbar.OptionEnableColorCodes(true),
@@ -422,7 +910,7 @@ func createPullProgressBar(total int64, model string) *progressbar.ProgressBar {
 		progressbar.OptionOnCompletion(func() {
 			fmt.Println()
 		}),
-		progressbar.OptionSetDescription("[cyan]"+fmt.Sprintf("[Msg] Pulling %s", model)+"[reset]"),
+		progressbar.OptionSetDescription("[cyan]"+"[Msg] "+desc+"[reset]"),
 		progressbar.OptionSetTheme(progressbar.Theme{
 			Saucer:        "[yellow]=[reset]",
 			SaucerHead:    "[yellow]>[reset]",
@@ -0,0 +1,59 @@
+package ollama
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFullName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"llama3", "llama3:latest"},
+		{"llama3:8b", "llama3:8b"},
+		{"qwen2.5-coder", "qwen2.5-coder:latest"},
+		{"qwen2.5-coder:7b", "qwen2.5-coder:7b"},
+		{"llama3.1:8b-instruct-q4_K_M", "llama3.1:8b-instruct-q4_K_M"},
+		{"hf.co/bartowski/Llama-3.2-1B-Instruct-GGUF", "hf.co/bartowski/Llama-3.2-1B-Instruct-GGUF:latest"},
+		{"registry.local:5000/team/llama3", "registry.local:5000/team/llama3:latest"},
+		{"registry.local:5000/team/llama3:v2", "registry.local:5000/team/llama3:v2"},
+	}
+	for _, tt := range tests {
+		if got := FullName(tt.name); got != tt.want {
+			t.Errorf("FullName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTrimHistory(t *testing.T) {
+	sys := Message{Role: "system", Content: "s"}
+	u1 := Message{Role: "user", Content: "u1"}
+	a1 := Message{Role: "assistant", Content: "a1"}
+	u2 := Message{Role: "user", Content: "u2"}
+	a2 := Message{Role: "assistant", Content: "a2"}
+	u3 := Message{Role: "user", Content: "u3"}
+	history := []Message{sys, u1, a1, u2, a2, u3}
+
+	tests := []struct {
+		name string
+		max  int
+		want []Message
+	}{
+		{"no limit", 0, history},
+		{"limit above length", 10, history},
+		{"limit equal to length", 5, history},
+		{"odd limit keeps whole exchanges", 3, []Message{sys, u2, a2, u3}},
+		{"even limit drops the lone answer", 4, []Message{sys, u2, a2, u3}},
+		{"limit of one", 1, []Message{sys, u3}},
+		{"limit of two drops the lone answer", 2, []Message{sys, u3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := TrimHistory(append([]Message(nil), history...), tt.max)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("TrimHistory(%d) = %v, want %v", tt.max, got, tt.want)
+			}
+		})
+	}
+}
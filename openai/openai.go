@@ -0,0 +1,344 @@
+package openai
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/HanmaDevin/schlama/config"
+	"github.com/HanmaDevin/schlama/ollama"
+	"github.com/HanmaDevin/schlama/server"
+)
+
+// maxBodySize limits requests, images are sent inline.
+const maxBodySize = 32 << 20
+
+// Handler serves an OpenAI compatible API on top of ollama. The system
+// prompt, options and keep alive of the config are applied to every model,
+// the request wins where it sets them itself. If apiKey is not empty,
+// requests have to send it as bearer token.
+func Handler(apiKey string) http.Handler {
+	router := http.NewServeMux()
+	router.HandleFunc("GET /v1/models", modelsHandler)
+	router.HandleFunc("GET /v1/models/{model...}", modelHandler)
+	router.HandleFunc("POST /v1/chat/completions", chatHandler)
+	router.HandleFunc("POST /v1/embeddings", embeddingsHandler)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if apiKey != "" {
+			token, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if subtle.ConstantTimeCompare([]byte(token), []byte(apiKey)) != 1 {
+				writeError(w, http.StatusUnauthorized, "authentication_error", "invalid_api_key", "Invalid API key")
+				return
+			}
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, maxBodySize)
+		router.ServeHTTP(w, r)
+	})
+}
+
+func modelsHandler(w http.ResponseWriter, r *http.Request) {
+	models, err := ollama.LocalModels()
+	if err != nil {
+		writeError(w, http.StatusBadGateway, "api_error", "", err.Error())
+		return
+	}
+	list := modelList{Object: "list", Data: []model{}}
+	for _, m := range models {
+		list.Data = append(list.Data, model{ID: m, Object: "model", OwnedBy: "library"})
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func modelHandler(w http.ResponseWriter, r *http.Request) {
+	name := ollama.FullName(r.PathValue("model"))
+	if !exists(w, name) {
+		return
+	}
+	writeJSON(w, http.StatusOK, model{ID: name, Object: "model", OwnedBy: "library"})
+}
+
+func chatHandler(w http.ResponseWriter, r *http.Request) {
+	var req chatRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "", "Invalid request body: "+err.Error())
+		return
+	}
+	if req.Model == "" || len(req.Messages) == 0 {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "", "model and messages are required")
+		return
+	}
+	name := ollama.FullName(req.Model)
+	if !exists(w, name) {
+		return
+	}
+	body, err := chatBody(name, &req)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "api_error", "", err.Error())
+		return
+	}
+
+	id := "chatcmpl-" + randomID()
+	created := time.Now().Unix()
+	if req.Stream {
+		stream(w, r, body, req, id, created)
+		return
+	}
+
+	// the answer is collected from the chunks, Chat would clean it up for
+	// the terminal
+	var text strings.Builder
+	_, metrics, err := ollama.Chat(r.Context(), body, func(chunk string) {
+		server.Extend(w)
+		text.WriteString(chunk)
+	})
+	if err != nil {
+		if r.Context().Err() == nil {
+			writeError(w, http.StatusBadGateway, "api_error", "", err.Error())
+		}
+		return
+	}
+	writeJSON(w, http.StatusOK, chatResponse{
+		ID:      id,
+		Object:  "chat.completion",
+		Created: created,
+		Model:   req.Model,
+		Choices: []chatChoice{{
+			Message:      &answer{Role: "assistant", Content: text.String()},
+			FinishReason: finishReason(metrics),
+		}},
+		Usage: usageOf(metrics),
+	})
+}
+
+// stream sends the answer as server-sent events, one chunk per piece of the
+// answer. The first chunk is delayed until ollama answers, so errors before
+// that still get a proper status.
+func stream(w http.ResponseWriter, r *http.Request, body *ollama.Ollama, req chatRequest, id string, created int64) {
+	rc := http.NewResponseController(w)
+	chunk := func(choices []chatChoice, u *usage) {
+		b, _ := json.Marshal(chatResponse{
+			ID:      id,
+			Object:  "chat.completion.chunk",
+			Created: created,
+			Model:   req.Model,
+			Choices: choices,
+			Usage:   u,
+		})
+		fmt.Fprintf(w, "data: %s\n\n", b)
+		rc.Flush()
+	}
+	started := false
+	start := func() {
+		if started {
+			return
+		}
+		started = true
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		chunk([]chatChoice{{Delta: &answer{Role: "assistant"}}}, nil)
+	}
+
+	_, metrics, err := ollama.Chat(r.Context(), body, func(text string) {
+		server.Extend(w)
+		start()
+		chunk([]chatChoice{{Delta: &answer{Content: text}}}, nil)
+	})
+	if r.Context().Err() != nil {
+		return
+	}
+	if err != nil {
+		if !started {
+			writeError(w, http.StatusBadGateway, "api_error", "", err.Error())
+			return
+		}
+		b, _ := json.Marshal(map[string]apiError{"error": {Message: err.Error(), Type: "api_error"}})
+		fmt.Fprintf(w, "data: %s\n\n", b)
+		return
+	}
+	start()
+	chunk([]chatChoice{{Delta: &answer{}, FinishReason: finishReason(metrics)}}, nil)
+	if req.StreamOptions != nil && req.StreamOptions.IncludeUsage {
+		chunk([]chatChoice{}, usageOf(metrics))
+	}
+	fmt.Fprint(w, "data: [DONE]\n\n")
+	rc.Flush()
+}
+
+// chatBody turns req into an ollama request for model with the settings of
+// the config applied.
+func chatBody(model string, req *chatRequest) (*ollama.Ollama, error) {
+	body, err := config.ReadConfigFor(model)
+	if err != nil {
+		return nil, err
+	}
+	// a system prompt of the client replaces the configured one
+	if slices.ContainsFunc(req.Messages, func(m chatMessage) bool { return m.Role == "system" || m.Role == "developer" }) {
+		body.Messages = nil
+	}
+	for _, m := range req.Messages {
+		role := m.Role
+		if role == "developer" {
+			role = "system"
+		}
+		body.Messages = append(body.Messages, ollama.Message{Role: role, Content: m.Content.Text, Images: m.Content.Images})
+	}
+
+	if body.Options == nil {
+		body.Options = map[string]any{}
+	}
+	set := func(name string, v any) {
+		body.Options[name] = v
+	}
+	if req.Temperature != nil {
+		set("temperature", *req.Temperature)
+	}
+	if req.TopP != nil {
+		set("top_p", *req.TopP)
+	}
+	if req.MaxCompletionTokens != nil {
+		set("num_predict", *req.MaxCompletionTokens)
+	} else if req.MaxTokens != nil {
+		set("num_predict", *req.MaxTokens)
+	}
+	if len(req.Stop) > 0 {
+		set("stop", []string(req.Stop))
+	}
+	if req.Seed != nil {
+		set("seed", *req.Seed)
+	}
+	if req.PresencePenalty != nil {
+		set("presence_penalty", *req.PresencePenalty)
+	}
+	if req.FrequencyPenalty != nil {
+		set("frequency_penalty", *req.FrequencyPenalty)
+	}
+	return body, nil
+}
+
+func embeddingsHandler(w http.ResponseWriter, r *http.Request) {
+	var req embeddingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "", "Invalid request body, input must be a string or a list of strings: "+err.Error())
+		return
+	}
+	if req.Model == "" || len(req.Input) == 0 {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "", "model and input are required")
+		return
+	}
+	if req.EncodingFormat != "" && req.EncodingFormat != "float" && req.EncodingFormat != "base64" {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "", "encoding_format must be float or base64")
+		return
+	}
+	name := ollama.FullName(req.Model)
+	if !exists(w, name) {
+		return
+	}
+	cfg, err := config.ReadConfigFor(name)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "api_error", "", err.Error())
+		return
+	}
+
+	resp, err := ollama.Embed(r.Context(), &ollama.EmbedRequest{
+		Model:     name,
+		Input:     req.Input,
+		KeepAlive: cfg.KeepAlive,
+		Options:   cfg.Options,
+	})
+	if err != nil {
+		if r.Context().Err() == nil {
+			writeError(w, http.StatusBadGateway, "api_error", "", err.Error())
+		}
+		return
+	}
+
+	out := embeddingResponse{
+		Object: "list",
+		Data:   []embedding{},
+		Model:  req.Model,
+		Usage:  usage{PromptTokens: resp.PromptEvalCount, TotalTokens: resp.PromptEvalCount},
+	}
+	for i, e := range resp.Embeddings {
+		var v any = e
+		if req.EncodingFormat == "base64" {
+			v = encodeFloats(e)
+		}
+		out.Data = append(out.Data, embedding{Object: "embedding", Index: i, Embedding: v})
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// encodeFloats encodes an embedding the way OpenAI does for base64.
+func encodeFloats(e []float32) string {
+	b := make([]byte, 4*len(e))
+	for i, f := range e {
+		binary.LittleEndian.PutUint32(b[4*i:], math.Float32bits(f))
+	}
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+// exists reports whether model is a local model and writes an error if it
+// is not.
+func exists(w http.ResponseWriter, model string) bool {
+	models, err := ollama.LocalModels()
+	if err != nil {
+		writeError(w, http.StatusBadGateway, "api_error", "", err.Error())
+		return false
+	}
+	if !slices.Contains(models, model) {
+		writeError(w, http.StatusNotFound, "invalid_request_error", "model_not_found",
+			fmt.Sprintf("The model %s does not exist, pull it with 'schlama pull %s'", model, model))
+		return false
+	}
+	return true
+}
+
+func finishReason(m ollama.Metrics) *string {
+	reason := "stop"
+	if m.DoneReason == "length" {
+		reason = "length"
+	}
+	return &reason
+}
+
+func usageOf(m ollama.Metrics) *usage {
+	return &usage{
+		PromptTokens:     m.PromptEvalCount,
+		CompletionTokens: m.EvalCount,
+		TotalTokens:      m.PromptEvalCount + m.EvalCount,
+	}
+}
+
+func randomID() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Error("Failed to write response: " + err.Error())
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, typ, code, msg string) {
+	e := apiError{Message: msg, Type: typ}
+	if code != "" {
+		e.Code = &code
+	}
+	writeJSON(w, status, map[string]apiError{"error": e})
+}
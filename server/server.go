@@ -0,0 +1,97 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/charmbracelet/log"
+)
+
+// Timeouts of the servers. Writing allows for slow models, answers may take
+// minutes. Handlers that wait for a model extend the write timeout with
+// Extend whenever a piece of the answer arrives.
+const (
+	readHeaderTimeout = 10 * time.Second
+	readTimeout       = 5 * time.Minute
+	writeTimeout      = 10 * time.Minute
+	idleTimeout       = 2 * time.Minute
+	shutdownTimeout   = 10 * time.Second
+)
+
+// New returns a server for h with timeouts and an access log.
+func New(h http.Handler) *http.Server {
+	return &http.Server{
+		Handler:           logRequests(h),
+		ReadHeaderTimeout: readHeaderTimeout,
+		ReadTimeout:       readTimeout,
+		WriteTimeout:      writeTimeout,
+		IdleTimeout:       idleTimeout,
+	}
+}
+
+// Extend moves the write deadline of the response writeTimeout into the
+// future, so an answer that keeps coming is not cut off while a stalled one
+// still is.
+func Extend(w http.ResponseWriter) {
+	// writers without deadlines keep the timeout of the server
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Now().Add(writeTimeout))
+}
+
+// Listen binds addr and port. If the port is taken, a free port is picked
+// instead so a second server can run next to the first.
+func Listen(addr string, port int) (net.Listener, error) {
+	if addr == "" {
+		addr = "localhost"
+	}
+	ln, err := net.Listen("tcp", net.JoinHostPort(addr, strconv.Itoa(port)))
+	if errors.Is(err, syscall.EADDRINUSE) {
+		log.Warn(fmt.Sprintf("Port %d is already in use, picking a free port", port))
+		ln, err = net.Listen("tcp", net.JoinHostPort(addr, "0"))
+	}
+	return ln, err
+}
+
+// URL is the URL of a server listening on addr. A wildcard address is
+// reached through localhost.
+func URL(addr *net.TCPAddr) string {
+	host := addr.IP.String()
+	if addr.IP.IsUnspecified() || addr.IP.IsLoopback() {
+		host = "localhost"
+	}
+	return "http://" + net.JoinHostPort(host, strconv.Itoa(addr.Port))
+}
+
+// Run serves srv on ln until SIGINT or SIGTERM and then shuts it down.
+// Requests get shutdownTimeout to finish, after that their connections are
+// closed, which cancels the answers that are still generated.
+func Run(srv *http.Server, ln net.Listener) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errc := make(chan error, 1)
+	go func() { errc <- srv.Serve(ln) }()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+	stop()
+	log.Info("Shutting down...")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Warn("Requests did not finish in time: " + err.Error())
+		return srv.Close()
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package session
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/HanmaDevin/schlama/markdown"
+)
+
+// Formats are the export formats understood by Render.
+var Formats = []string{"md", "json", "html"}
+
+// FormatFromPath picks the export format from the extension of path and
+// falls back to markdown.
+func FormatFromPath(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return "json"
+	case ".html", ".htm":
+		return "html"
+	default:
+		return "md"
+	}
+}
+
+// ContentType returns the MIME type of an export format.
+func ContentType(format string) string {
+	switch format {
+	case "json":
+		return "application/json"
+	case "html":
+		return "text/html; charset=utf-8"
+	default:
+		return "text/markdown; charset=utf-8"
+	}
+}
+
+// Render writes the transcript of s in the given format, including the
+// model, options and timestamps.
+func Render(w io.Writer, s *Session, format string) error {
+	switch format {
+	case "md":
+		return renderMarkdown(w, s)
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		return enc.Encode(s)
+	case "html":
+		return exportTemplate.Execute(w, s)
+	default:
+		return fmt.Errorf("unknown format %q, use one of %s", format, strings.Join(Formats, ", "))
+	}
+}
+
+// TitleOrID returns the title of the session or a title built from its id.
+func (s *Session) TitleOrID() string {
+	if s.Title != "" {
+		return s.Title
+	}
+	return "Conversation " + s.ID
+}
+
+// OptionList returns the options as sorted "name=value" pairs.
+func (s *Session) OptionList() []string {
+	var opts []string
+	for k, v := range s.Options {
+		opts = append(opts, fmt.Sprintf("%s=%v", k, v))
+	}
+	slices.Sort(opts)
+	return opts
+}
+
+func renderMarkdown(w io.Writer, s *Session) error {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# %s\n\n", s.TitleOrID())
+	fmt.Fprintf(&sb, "- **Model:** %s\n", s.Model)
+	fmt.Fprintf(&sb, "- **Started:** %s\n", s.Created.Format(time.RFC1123))
+	fmt.Fprintf(&sb, "- **Updated:** %s\n", s.Updated.Format(time.RFC1123))
+	if opts := s.OptionList(); len(opts) > 0 {
+		fmt.Fprintf(&sb, "- **Options:** %s\n", strings.Join(opts, ", "))
+	}
+	if s.System != "" {
+		fmt.Fprintf(&sb, "- **System:** %s\n", s.System)
+	}
+
+	for _, m := range s.Branch() {
+		sb.WriteString("\n---\n\n")
+		fmt.Fprintf(&sb, "### %s · %s\n\n", speaker(m), m.Time.Format("15:04:05"))
+		sb.WriteString(strings.TrimSpace(m.Content) + "\n")
+		if len(m.Images) > 0 {
+			fmt.Fprintf(&sb, "\n_%d image(s) attached_\n", len(m.Images))
+		}
+		for _, a := range m.Attachments {
+			fmt.Fprintf(&sb, "\n_Attached %s (%s)_\n", a.Name, a.Type)
+		}
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func speaker(m Message) string {
+	switch m.Role {
+	case "assistant":
+		if m.Model != "" {
+			return "Assistant (" + m.Model + ")"
+		}
+		return "Assistant"
+	case "user":
+		return "User"
+	default:
+		return m.Role
+	}
+}
+
+// ImageURL turns a base64 image into a data url.
+func ImageURL(encoded string) template.URL {
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return ""
+	}
+	return template.URL("data:" + http.DetectContentType(data) + ";base64," + encoded)
+}
+
+var exportTemplate = template.Must(template.New("export").Funcs(template.FuncMap{
+	"speaker":  speaker,
+	"imageURL": ImageURL,
+	"join":     strings.Join,
+	"time": func(t time.Time) string {
+		return t.Format(time.RFC1123)
+	},
+	"clock": func(t time.Time) string {
+		return t.Format("15:04:05")
+	},
+	"markdown": markdown.HTML,
+	"markdownCSS": func() template.CSS {
+		return template.CSS(markdown.CSS())
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="UTF-8" />
+  <title>{{.TitleOrID}}</title>
+  <style>
+    body { font-family: sans-serif; background: #1e1e2e; color: #cdd6f4; max-width: 900px; margin: 2rem auto; padding: 0 1rem; }
+    dl { display: grid; grid-template-columns: max-content auto; gap: 0.25rem 1rem; color: #a6adc8; }
+    dt { font-weight: bold; }
+    .msg { margin: 1rem 0; padding: 8px 16px; border-radius: 16px; max-width: 70%; color: #1e1e2e; }
+    .user { background: #89b4fa; margin-left: auto; border-radius: 16px 0 16px 16px; }
+    .assistant { background: #a6e3a1; border-radius: 0 16px 16px 16px; }
+    .meta { font-size: 0.75rem; opacity: 0.7; }
+    .content { white-space: pre-wrap; }
+    img { max-width: 100%; border-radius: 8px; }
+    {{markdownCSS}}
+  </style>
+</head>
+<body>
+  <h1>{{.TitleOrID}}</h1>
+  <dl>
+    <dt>Model</dt><dd>{{.Model}}</dd>
+    <dt>Started</dt><dd>{{time .Created}}</dd>
+    <dt>Updated</dt><dd>{{time .Updated}}</dd>
+    {{with .OptionList}}<dt>Options</dt><dd>{{join . ", "}}</dd>{{end}}
+    {{with .System}}<dt>System</dt><dd>{{.}}</dd>{{end}}
+  </dl>
+  {{range .Branch}}
+  <div class="msg {{.Role}}">
+    <div class="meta">{{speaker .}} · {{clock .Time}}</div>
+    {{if eq .Role "assistant"}}
+    <div class="markdown">{{markdown .Content}}</div>
+    {{else}}
+    <div class="content">{{.Content}}</div>
+    {{end}}
+    {{range .Images}}<img src="{{imageURL .}}" alt="attached image" />{{end}}
+    {{range .Attachments}}<div class="meta">Attached {{.Name}} ({{.Type}})</div>{{end}}
+  </div>
+  {{end}}
+</body>
+</html>
+`))
@@ -0,0 +1,410 @@
+package session
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/HanmaDevin/schlama/ollama"
+)
+
+// Message is one turn of a conversation. Messages form a tree: editing a
+// prompt or asking for a new answer starts a new branch next to the old one.
+type Message struct {
+	ID int `json:"id"`
+	// Parent is the id of the previous message, 0 for the first one.
+	Parent  int      `json:"parent,omitempty"`
+	Role    string   `json:"role"`
+	Content string   `json:"content"`
+	Images  []string `json:"images,omitempty"`
+	// Attachments are the files sent along with a prompt.
+	Attachments []Attachment `json:"attachments,omitempty"`
+	Model       string       `json:"model,omitempty"`
+	Time        time.Time    `json:"time"`
+}
+
+// Attachment is a file attached to a prompt. Text is what the model reads of
+// it, images are stored in the images of the message instead.
+type Attachment struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Size int64  `json:"size"`
+	Text string `json:"text,omitempty"`
+}
+
+// Session is a conversation from 'schlama run' or the web chat.
+type Session struct {
+	ID      string         `json:"id"`
+	Title   string         `json:"title,omitempty"`
+	Source  string         `json:"source"`
+	Model   string         `json:"model"`
+	System  string         `json:"system,omitempty"`
+	Options map[string]any `json:"options,omitempty"`
+	Created time.Time      `json:"created"`
+	Updated time.Time      `json:"updated"`
+	// Pinned conversations are listed first in the web chat.
+	Pinned bool `json:"pinned,omitempty"`
+	// Head is the id of the last message of the active branch.
+	Head     int       `json:"head"`
+	Messages []Message `json:"messages"`
+}
+
+// New starts a session for the given request. source tells where the
+// conversation happens, e.g. "run" or "web".
+func New(req *ollama.Ollama, source string) *Session {
+	now := time.Now()
+	s := &Session{
+		ID:      newID(now),
+		Source:  source,
+		Created: now,
+		Updated: now,
+	}
+	s.Configure(req)
+	return s
+}
+
+// Configure records the model, system prompt and options of req.
+func (s *Session) Configure(req *ollama.Ollama) {
+	s.Model = req.Model
+	s.Options = req.Options
+	s.System = ""
+	for _, m := range req.Messages {
+		if m.Role == "system" {
+			s.System = m.Content
+		}
+	}
+}
+
+// Add appends a message to the active branch. Assistant messages record the
+// model that wrote them.
+func (s *Session) Add(msg ollama.Message) {
+	s.add(s.Head, msg)
+}
+
+// AddPrompt appends a prompt with attached files to the active branch.
+func (s *Session) AddPrompt(content string, images []string, attachments []Attachment) {
+	s.add(s.Head, ollama.Message{Role: "user", Content: content, Images: images})
+	s.Messages[len(s.Messages)-1].Attachments = attachments
+}
+
+func (s *Session) add(parent int, msg ollama.Message) {
+	m := Message{
+		ID:      len(s.Messages) + 1,
+		Parent:  parent,
+		Role:    msg.Role,
+		Content: msg.Content,
+		Images:  msg.Images,
+		Time:    time.Now(),
+	}
+	if msg.Role == "assistant" {
+		m.Model = s.Model
+	}
+	s.Messages = append(s.Messages, m)
+	s.Head = m.ID
+	s.Updated = m.Time
+}
+
+// Get returns the message with the given id.
+func (s *Session) Get(id int) (Message, bool) {
+	// ids are assigned in order, starting at 1
+	if id < 1 || id > len(s.Messages) {
+		return Message{}, false
+	}
+	return s.Messages[id-1], true
+}
+
+// Branch returns the messages of the active branch, the first one first.
+func (s *Session) Branch() []Message {
+	var branch []Message
+	for id := s.Head; id != 0; {
+		m, ok := s.Get(id)
+		if !ok {
+			break
+		}
+		branch = append(branch, m)
+		id = m.Parent
+	}
+	slices.Reverse(branch)
+	return branch
+}
+
+// Prompts returns the prompts of the active branch. /edit N in the shell
+// counts prompts in this order, starting at 1.
+func (s *Session) Prompts() []Message {
+	var prompts []Message
+	for _, m := range s.Branch() {
+		if m.Role == "user" {
+			prompts = append(prompts, m)
+		}
+	}
+	return prompts
+}
+
+// Checkout makes the branch that ends with the message id the active one.
+// Id 0 selects the empty conversation.
+func (s *Session) Checkout(id int) error {
+	if _, ok := s.Get(id); !ok && id != 0 {
+		return fmt.Errorf("message %d not found", id)
+	}
+	s.Head = id
+	s.Updated = time.Now()
+	return nil
+}
+
+// Edit starts a new branch with content in place of the prompt id. The
+// images and attachments of the prompt are kept. The old branch stays in the
+// session.
+func (s *Session) Edit(id int, content string) error {
+	m, ok := s.Get(id)
+	if !ok || m.Role != "user" {
+		return fmt.Errorf("prompt %d not found", id)
+	}
+	s.add(m.Parent, ollama.Message{
+		Role:    "user",
+		Content: content,
+		Images:  m.Images,
+	})
+	s.Messages[len(s.Messages)-1].Attachments = m.Attachments
+	return nil
+}
+
+// Regenerate makes the prompt that the answer id replied to the head, so a
+// new answer starts a new branch next to it.
+func (s *Session) Regenerate(id int) error {
+	m, ok := s.Get(id)
+	if !ok || m.Role != "assistant" {
+		return fmt.Errorf("answer %d not found", id)
+	}
+	return s.Checkout(m.Parent)
+}
+
+// History returns the messages of the active branch in the format of the
+// ollama api. The text of attachments is appended to their prompt.
+func (s *Session) History() []ollama.Message {
+	branch := s.Branch()
+	history := make([]ollama.Message, 0, len(branch))
+	for _, m := range branch {
+		content := m.Content
+		for _, a := range m.Attachments {
+			if a.Text != "" {
+				content += "\n\nFile: " + a.Name + "\n" + a.Text
+			}
+		}
+		history = append(history, ollama.Message{
+			Role:    m.Role,
+			Content: content,
+			Images:  m.Images,
+		})
+	}
+	return history
+}
+
+// Dir returns the directory sessions are stored in,
+// $XDG_DATA_HOME/schlama/sessions or ~/.local/share/schlama/sessions.
+func Dir() string {
+	if xdg := os.Getenv("XDG_DATA_HOME"); xdg != "" && filepath.IsAbs(xdg) {
+		return filepath.Join(xdg, "schlama", "sessions")
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".local", "share", "schlama", "sessions")
+}
+
+// Save writes the session to its file in Dir.
+func Save(s *Session) error {
+	if err := os.MkdirAll(Dir(), 0755); err != nil {
+		return fmt.Errorf("failed to create session directory: %w", err)
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode session: %w", err)
+	}
+
+	// write to a temporary file first so a crash never leaves half a session
+	tmp, err := os.CreateTemp(Dir(), ".session-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to write session: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write session: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write session: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(Dir(), s.ID+".json")); err != nil {
+		return fmt.Errorf("failed to write session: %w", err)
+	}
+	return nil
+}
+
+// validID matches session ids and their prefixes. Anything else could name
+// a file outside Dir or be a glob pattern.
+var validID = regexp.MustCompile(`^[\w-]+$`)
+
+func checkID(id string) error {
+	if !validID.MatchString(id) {
+		return fmt.Errorf("invalid session id %q", id)
+	}
+	return nil
+}
+
+// notFoundError reports a session that is not stored. It matches
+// fs.ErrNotExist.
+type notFoundError string
+
+func (id notFoundError) Error() string { return "session " + string(id) + " not found" }
+
+func (notFoundError) Is(target error) bool { return target == fs.ErrNotExist }
+
+// Load reads the session with the given id. A unique prefix of the id is
+// enough.
+func Load(id string) (*Session, error) {
+	if err := checkID(id); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(Dir(), id+".json"))
+	if os.IsNotExist(err) {
+		matches, _ := filepath.Glob(filepath.Join(Dir(), id+"*.json"))
+		switch len(matches) {
+		case 0:
+			return nil, notFoundError(id)
+		case 1:
+			data, err = os.ReadFile(matches[0])
+		default:
+			return nil, fmt.Errorf("session id %s is ambiguous, %d sessions match", id, len(matches))
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session: %w", err)
+	}
+
+	var s Session
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to decode session %s: %w", id, err)
+	}
+	s.upgrade()
+	return &s, nil
+}
+
+// Delete removes the stored session with the given id.
+func Delete(id string) error {
+	if err := checkID(id); err != nil {
+		return err
+	}
+	if err := os.Remove(filepath.Join(Dir(), id+".json")); err != nil {
+		if os.IsNotExist(err) {
+			return notFoundError(id)
+		}
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	return nil
+}
+
+// List returns all stored sessions, the most recently updated first.
+func List() ([]*Session, error) {
+	files, err := filepath.Glob(filepath.Join(Dir(), "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var sessions []*Session
+	for _, f := range files {
+		s, err := Load(strings.TrimSuffix(filepath.Base(f), ".json"))
+		if err != nil {
+			continue
+		}
+		sessions = append(sessions, s)
+	}
+	slices.SortFunc(sessions, func(a, b *Session) int {
+		return b.Updated.Compare(a.Updated)
+	})
+	return sessions, nil
+}
+
+// Label returns the title of the session, or its first prompt if it has no
+// title yet.
+func (s *Session) Label() string {
+	if s.Title != "" || len(s.Messages) == 0 {
+		return s.Title
+	}
+	return strings.Join(strings.Fields(s.Messages[0].Content), " ")
+}
+
+// Matches reports whether the title or a message of the session contains
+// query, ignoring case.
+func (s *Session) Matches(query string) bool {
+	query = strings.ToLower(query)
+	if strings.Contains(strings.ToLower(s.Title), query) {
+		return true
+	}
+	return slices.ContainsFunc(s.Messages, func(m Message) bool {
+		return strings.Contains(strings.ToLower(m.Content), query)
+	})
+}
+
+func newID(t time.Time) string {
+	b := make([]byte, 3)
+	rand.Read(b)
+	return t.Format("20060102-150405") + "-" + hex.EncodeToString(b)
+}
+
+// Undo moves the head before the last prompt of the active branch. It
+// reports whether there was a prompt to undo.
+func (s *Session) Undo() bool {
+	branch := s.Branch()
+	for i := len(branch) - 1; i >= 0; i-- {
+		if branch[i].Role == "user" {
+			s.Head = branch[i].Parent
+			s.Updated = time.Now()
+			return true
+		}
+	}
+	return false
+}
+
+// Retry moves the head from the last answer back to its prompt, so a new
+// answer starts a new branch. It reports whether the active branch now ends
+// with a prompt.
+func (s *Session) Retry() bool {
+	m, ok := s.Get(s.Head)
+	if ok && m.Role == "assistant" {
+		s.Head = m.Parent
+		s.Updated = time.Now()
+		m, ok = s.Get(s.Head)
+	}
+	return ok && m.Role == "user"
+}
+
+// Drop removes the message id if it is the last one that was added, e.g. a
+// prompt the model could not answer. Callers check out the previous head
+// afterwards.
+func (s *Session) Drop(id int) {
+	if id == 0 || id != len(s.Messages) {
+		return
+	}
+	s.Messages = s.Messages[:id-1]
+	if s.Head == id {
+		s.Head = 0
+	}
+}
+
+// upgrade turns the flat message list of sessions stored before messages had
+// ids into a single branch.
+func (s *Session) upgrade() {
+	if len(s.Messages) == 0 || s.Messages[0].ID != 0 {
+		return
+	}
+	for i := range s.Messages {
+		s.Messages[i].ID = i + 1
+		s.Messages[i].Parent = i
+	}
+	s.Head = len(s.Messages)
+}
@@ -0,0 +1,277 @@
+package session
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/HanmaDevin/schlama/ollama"
+)
+
+func prompt(content string) ollama.Message {
+	return ollama.Message{Role: "user", Content: content}
+}
+
+func reply(content string) ollama.Message {
+	return ollama.Message{Role: "assistant", Content: content}
+}
+
+// contents returns the contents of the active branch of s.
+func contents(s *Session) []string {
+	var c []string
+	for _, m := range s.Branch() {
+		c = append(c, m.Content)
+	}
+	return c
+}
+
+func TestBranches(t *testing.T) {
+	tests := []struct {
+		name string
+		// steps run on a session that holds the exchanges u1/a1 and u2/a2
+		steps func(t *testing.T, s *Session)
+		want  []string
+	}{
+		{
+			name:  "linear",
+			steps: func(t *testing.T, s *Session) {},
+			want:  []string{"u1", "a1", "u2", "a2"},
+		},
+		{
+			name: "edit the first prompt",
+			steps: func(t *testing.T, s *Session) {
+				if err := s.Edit(1, "u1 edited"); err != nil {
+					t.Fatal(err)
+				}
+				s.Add(reply("a1 new"))
+			},
+			want: []string{"u1 edited", "a1 new"},
+		},
+		{
+			name: "edit the last prompt",
+			steps: func(t *testing.T, s *Session) {
+				if err := s.Edit(3, "u2 edited"); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: []string{"u1", "a1", "u2 edited"},
+		},
+		{
+			name: "regenerate an answer",
+			steps: func(t *testing.T, s *Session) {
+				if err := s.Regenerate(4); err != nil {
+					t.Fatal(err)
+				}
+				s.Add(reply("a2 again"))
+			},
+			want: []string{"u1", "a1", "u2", "a2 again"},
+		},
+		{
+			name: "check out the old branch after an edit",
+			steps: func(t *testing.T, s *Session) {
+				if err := s.Edit(3, "u2 edited"); err != nil {
+					t.Fatal(err)
+				}
+				s.Add(reply("a2 new"))
+				if err := s.Checkout(4); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: []string{"u1", "a1", "u2", "a2"},
+		},
+		{
+			name: "undo",
+			steps: func(t *testing.T, s *Session) {
+				if !s.Undo() {
+					t.Fatal("Undo() = false")
+				}
+			},
+			want: []string{"u1", "a1"},
+		},
+		{
+			name: "undo twice",
+			steps: func(t *testing.T, s *Session) {
+				s.Undo()
+				if !s.Undo() {
+					t.Fatal("Undo() = false")
+				}
+				if s.Undo() {
+					t.Error("Undo() of an empty branch = true")
+				}
+			},
+			want: nil,
+		},
+		{
+			name: "undo then prompt starts a branch",
+			steps: func(t *testing.T, s *Session) {
+				s.Undo()
+				s.Add(prompt("u2 other"))
+				s.Add(reply("a2 other"))
+			},
+			want: []string{"u1", "a1", "u2 other", "a2 other"},
+		},
+		{
+			name: "undo of an unanswered prompt",
+			steps: func(t *testing.T, s *Session) {
+				s.Add(prompt("u3"))
+				s.Undo()
+			},
+			want: []string{"u1", "a1", "u2", "a2"},
+		},
+		{
+			name: "retry",
+			steps: func(t *testing.T, s *Session) {
+				if !s.Retry() {
+					t.Fatal("Retry() = false")
+				}
+				s.Add(reply("a2 retried"))
+			},
+			want: []string{"u1", "a1", "u2", "a2 retried"},
+		},
+		{
+			name: "retry of an unanswered prompt",
+			steps: func(t *testing.T, s *Session) {
+				s.Add(prompt("u3"))
+				if !s.Retry() {
+					t.Fatal("Retry() = false")
+				}
+			},
+			want: []string{"u1", "a1", "u2", "a2", "u3"},
+		},
+		{
+			name: "drop a failed prompt",
+			steps: func(t *testing.T, s *Session) {
+				head := s.Head
+				s.Add(prompt("u3"))
+				s.Drop(s.Head)
+				if err := s.Checkout(head); err != nil {
+					t.Fatal(err)
+				}
+				if len(s.Messages) != 4 {
+					t.Errorf("Drop() kept %d messages, want 4", len(s.Messages))
+				}
+			},
+			want: []string{"u1", "a1", "u2", "a2"},
+		},
+		{
+			name: "drop only removes the last message",
+			steps: func(t *testing.T, s *Session) {
+				s.Drop(2)
+				if len(s.Messages) != 4 {
+					t.Errorf("Drop(2) left %d messages, want 4", len(s.Messages))
+				}
+			},
+			want: []string{"u1", "a1", "u2", "a2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(&ollama.Ollama{Model: "llama3:latest"}, "test")
+			for _, m := range []ollama.Message{prompt("u1"), reply("a1"), prompt("u2"), reply("a2")} {
+				s.Add(m)
+			}
+			tt.steps(t, s)
+			if got := contents(s); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("branch = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBranchErrors(t *testing.T) {
+	s := New(&ollama.Ollama{Model: "llama3:latest"}, "test")
+	s.Add(prompt("u1"))
+	s.Add(reply("a1"))
+
+	if err := s.Edit(2, "x"); err == nil {
+		t.Error("Edit() of an answer succeeded")
+	}
+	if err := s.Edit(9, "x"); err == nil {
+		t.Error("Edit() of a missing message succeeded")
+	}
+	if err := s.Regenerate(1); err == nil {
+		t.Error("Regenerate() of a prompt succeeded")
+	}
+	if err := s.Checkout(9); err == nil {
+		t.Error("Checkout() of a missing message succeeded")
+	}
+	if err := s.Checkout(0); err != nil || s.Branch() != nil {
+		t.Errorf("Checkout(0) = %v, branch %v", err, s.Branch())
+	}
+}
+
+func TestEditKeepsAttachments(t *testing.T) {
+	s := New(&ollama.Ollama{Model: "llama3:latest"}, "test")
+	s.AddPrompt("read this", []string{"aW1n"}, []Attachment{{Name: "a.txt", Type: "text/plain", Text: "hello"}})
+	if err := s.Edit(1, "read this again"); err != nil {
+		t.Fatal(err)
+	}
+	want := []ollama.Message{{Role: "user", Content: "read this again\n\nFile: a.txt\nhello", Images: []string{"aW1n"}}}
+	if got := s.History(); !reflect.DeepEqual(got, want) {
+		t.Errorf("History() = %#v, want %#v", got, want)
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	s := New(&ollama.Ollama{Model: "llama3:latest"}, "test")
+	s.Add(prompt("u1"))
+	s.Add(reply("a1"))
+	if err := Save(s); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []string{s.ID, s.ID[:10]} {
+		loaded, err := Load(id)
+		if err != nil {
+			t.Fatalf("Load(%q) error = %v", id, err)
+		}
+		if loaded.ID != s.ID || !reflect.DeepEqual(contents(loaded), []string{"u1", "a1"}) {
+			t.Errorf("Load(%q) = %s %q", id, loaded.ID, contents(loaded))
+		}
+	}
+
+	if err := Delete(s.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(s.ID); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Load() of a deleted session error = %v, want fs.ErrNotExist", err)
+	}
+	if err := Delete(s.ID); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Delete() of a deleted session error = %v, want fs.ErrNotExist", err)
+	}
+}
+
+func TestLoadUpgradesFlatSessions(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	if err := os.MkdirAll(Dir(), 0755); err != nil {
+		t.Fatal(err)
+	}
+	old := `{"id":"20240101-000000-abcdef","source":"run","model":"llama3:latest","messages":[` +
+		`{"role":"user","content":"u1"},{"role":"assistant","content":"a1"}]}`
+	if err := os.WriteFile(filepath.Join(Dir(), "20240101-000000-abcdef.json"), []byte(old), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := Load("20240101-000000-abcdef")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := contents(s); !reflect.DeepEqual(got, []string{"u1", "a1"}) {
+		t.Errorf("branch of an upgraded session = %q", got)
+	}
+}
+
+func TestInvalidIDs(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	for _, id := range []string{"", "*", "../config", "a/b", `a\b`, "a.json", "a b"} {
+		if _, err := Load(id); err == nil || errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("Load(%q) error = %v, want an invalid id", id, err)
+		}
+		if err := Delete(id); err == nil || errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("Delete(%q) error = %v, want an invalid id", id, err)
+		}
+	}
+}